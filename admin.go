@@ -0,0 +1,120 @@
+package logmanager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type adminModule struct {
+	Name  string `json:"name"`
+	Level string `json:"level"`
+	Rule  string `json:"rule,omitempty"`
+}
+
+type adminStatus struct {
+	Modules []adminModule `json:"modules"`
+	Writers []string      `json:"writers"`
+}
+
+type adminChange struct {
+	Changed   []string   `json:"changed"`
+	Level     string     `json:"level"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// AdminHandler returns a http.Handler to look at and change log levels of a running process.
+//
+// GET lists every known module with its level and the spec entry it came from, as well as the writers in use.
+//
+// PUT or POST changes the level of a module, parameters are read from the query or a form body:
+//
+//	module    the module to change, it must be in use unless children is true
+//	level     the new level, e.g. "trace"
+//	children  if true, every module below module is changed as well, including ones first used later on
+//	expires   optional duration (e.g. "10m") after which the levels from the spec are restored
+//
+// for example, to turn on tracing for foo.bar and everything below it for ten minutes:
+//
+//	curl -X PUT 'localhost:8080/debug/logs?module=foo.bar&level=trace&children=true&expires=10m'
+func AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			writeAdminJSON(w, adminStatusNow())
+		case http.MethodPut, http.MethodPost:
+			change, err := adminSetLevel(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			writeAdminJSON(w, change)
+		default:
+			w.Header().Set("Allow", "GET, HEAD, PUT, POST")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func adminStatusNow() adminStatus {
+	status := adminStatus{Modules: []adminModule{}, Writers: []string{}}
+	for _, info := range Loggers() {
+		status.Modules = append(status.Modules, adminModule{Name: info.Name, Level: info.Level.String(), Rule: info.Rule})
+	}
+	for _, writer := range Writers() {
+		status.Writers = append(status.Writers, fmt.Sprintf("%T", writer))
+	}
+	return status
+}
+
+func adminSetLevel(r *http.Request) (adminChange, error) {
+	module := r.FormValue("module")
+	if module == "" {
+		return adminChange{}, errors.New("missing module")
+	}
+
+	level, err := ParseLevel(r.FormValue("level"))
+	if err != nil {
+		return adminChange{}, err
+	}
+
+	var children bool
+	if v := r.FormValue("children"); v != "" {
+		if children, err = strconv.ParseBool(v); err != nil {
+			return adminChange{}, fmt.Errorf("invalid children %q: %w", v, err)
+		}
+	}
+
+	if !children && !knownModule(module) {
+		return adminChange{}, fmt.Errorf("unknown module %q, use children=true to change modules that aren't in use yet", module)
+	}
+
+	var expiry time.Duration
+	if v := r.FormValue("expires"); v != "" {
+		if expiry, err = time.ParseDuration(v); err != nil || expiry <= 0 {
+			return adminChange{}, fmt.Errorf("invalid expires %q, expected a positive duration like 10m", v)
+		}
+	}
+
+	change := adminChange{
+		Changed: SetModuleLevel(module, level, children, expiry),
+		Level:   level.String(),
+	}
+	if expiry > 0 {
+		expiresAt := time.Now().UTC().Add(expiry)
+		change.ExpiresAt = &expiresAt
+	}
+	return change, nil
+}
+
+func writeAdminJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		println("Warning, could not write admin response:", err.Error())
+	}
+}
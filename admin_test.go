@@ -0,0 +1,138 @@
+package logmanager
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetModuleLevel(t *testing.T) {
+	assert := assert.New(t)
+
+	setTestSpec(t, "")
+	parent := GetLogger("admin.level")
+	child := GetLogger("admin.level.child")
+	other := GetLogger("admin.levelother")
+
+	changed := SetModuleLevel("admin.level", Trace, true, 0)
+	assert.Equal([]string{"admin.level", "admin.level.child"}, changed)
+	assert.Equal(Trace, parent.LogLevel())
+	assert.Equal(Trace, child.LogLevel())
+	assert.Equal(Info, other.LogLevel(), "children are split on dots")
+
+	SetModuleLevel("admin.level", Error, false, 10*time.Millisecond)
+	assert.Equal(Error, parent.LogLevel())
+	assert.Equal(Trace, child.LogLevel())
+	assert.Eventually(func() bool { return parent.LogLevel() == Trace }, time.Second, time.Millisecond,
+		"the previous level should be restored after the expiry")
+
+	// a later change wins over an expiring one
+	SetModuleLevel("admin.level.child", Error, false, 10*time.Millisecond)
+	SetModuleLevel("admin.level.child", Warning, false, 0)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(Warning, child.LogLevel())
+
+	// unique, so the module is new every time the test runs
+	fresh := "admin.fresh" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	assert.Empty(SetModuleLevel(fresh, Debug, false, 0), "modules can be configured before they are used")
+	assert.False(knownModule(fresh), "without being added to the registry")
+	newLogger := GetLogger(fresh)
+	assert.Equal(Debug, newLogger.LogLevel())
+}
+
+func TestSetModuleLevelPrefix(t *testing.T) {
+	assert := assert.New(t)
+
+	setTestSpec(t, "admin.prefix.spec=Warning,rate=10/s")
+	existing := GetLogger("admin.prefix.existing")
+
+	assert.Contains(SetModuleLevel("admin.prefix", Trace, true, 50*time.Millisecond), "admin.prefix.existing")
+	later := GetLogger("admin.prefix.later")
+	spec := GetLogger("admin.prefix.spec")
+	other := GetLogger("admin.prefixother")
+	assert.Equal(Trace, existing.LogLevel())
+	assert.Equal(Trace, later.LogLevel(), "modules first used after the change get the level as well")
+	assert.Equal(Trace, spec.LogLevel())
+	assert.Equal(Info, other.LogLevel())
+
+	assert.Eventually(func() bool { return later.LogLevel() == Info }, time.Second, time.Millisecond,
+		"the level from the spec should be restored after the expiry")
+	assert.Equal(Info, existing.LogLevel())
+	assert.Equal(Warning, spec.LogLevel())
+	assert.Contains(Loggers(), LoggerInfo{Name: "admin.prefix.spec", Level: Warning, Rule: "admin.prefix.spec=warn,rate=10/s"})
+	assert.NotNil(spec.limits.Load(), "the rate limits of the rule are kept")
+	newer := GetLogger("admin.prefix.newer")
+	assert.Equal(Info, newer.LogLevel(), "an expired change doesn't apply to new modules")
+
+	// an expired change restores the one before it
+	SetModuleLevel("admin.prefix", Debug, true, 0)
+	SetModuleLevel("admin.prefix.existing", Error, false, 10*time.Millisecond)
+	assert.Equal(Error, existing.LogLevel())
+	assert.Eventually(func() bool { return existing.LogLevel() == Debug }, time.Second, time.Millisecond)
+
+	// the spec replaces every change
+	setTestSpec(t, "")
+	latest := GetLogger("admin.prefix.latest")
+	assert.Equal(Info, existing.LogLevel())
+	assert.Equal(Info, latest.LogLevel())
+}
+
+func TestAdminHandler(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	setTestSpec(t, "")
+	logger := GetLogger("admin.http")
+	handler := AdminHandler()
+
+	do := func(method, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		return rec
+	}
+
+	rec := do(http.MethodPut, "/?module=admin.http&level=trace&expires=1h")
+	require.Equal(http.StatusOK, rec.Code, rec.Body.String())
+	var change adminChange
+	require.NoError(json.Unmarshal(rec.Body.Bytes(), &change))
+	assert.Equal([]string{"admin.http"}, change.Changed)
+	assert.Equal("trace", change.Level)
+	assert.NotNil(change.ExpiresAt)
+	assert.Equal(Trace, logger.LogLevel())
+
+	rec = do(http.MethodGet, "/")
+	require.Equal(http.StatusOK, rec.Code)
+	assert.Equal("application/json", rec.Header().Get("Content-Type"))
+	var status adminStatus
+	require.NoError(json.Unmarshal(rec.Body.Bytes(), &status))
+	assert.Contains(status.Modules, adminModule{Name: "admin.http", Level: "trace"})
+	assert.NotEmpty(status.Writers)
+
+	form := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("module=admin.http&level=warn"))
+	form.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, form)
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Equal(Warning, logger.LogLevel())
+
+	assert.Equal(http.StatusBadRequest, do(http.MethodPut, "/?module=admin.typo&level=trace").Code,
+		"modules that aren't in use can only be changed as a prefix")
+	assert.NotContains(adminStatusNow().Modules, adminModule{Name: "admin.typo", Level: "info"})
+	rec = do(http.MethodPut, "/?module=admin.later&level=debug&children=true")
+	assert.Equal(http.StatusOK, rec.Code, rec.Body.String())
+	later := GetLogger("admin.later.module")
+	assert.Equal(Debug, later.LogLevel())
+
+	assert.Equal(http.StatusBadRequest, do(http.MethodPut, "/?level=trace").Code)
+	assert.Equal(http.StatusBadRequest, do(http.MethodPut, "/?module=admin.http&level=loud").Code)
+	assert.Equal(http.StatusBadRequest, do(http.MethodPut, "/?module=admin.http&level=info&expires=soon").Code)
+	assert.Equal(http.StatusBadRequest, do(http.MethodPut, "/?module=admin.http&level=info&children=maybe").Code)
+	assert.Equal(http.StatusMethodNotAllowed, do(http.MethodDelete, "/").Code)
+}
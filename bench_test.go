@@ -0,0 +1,151 @@
+package logmanager
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// discardWriter drops everything, so benchmarks only measure the logger itself
+type discardWriter struct{}
+
+func (discardWriter) BuildTheme(string) ColorTheme                                  { return ColorTheme{} }
+func (discardWriter) WriteRecord(ColorTheme, *Record)                               {}
+func (discardWriter) Log(Level, ColorTheme, string, string, int, time.Time, string) {}
+
+func benchLogger(tb testing.TB, writers ...Writer) Logger {
+	tb.Helper()
+	orig := Writers()
+	ReplaceWriters(writers...)
+	tb.Cleanup(func() { ReplaceWriters(orig...) })
+
+	logger := GetLogger("bench")
+	logger.SetLogLevel(Info)
+	return logger
+}
+
+func discardConsole() *ConsoleWriter {
+	return &ConsoleWriter{out: io.Discard}
+}
+
+func BenchmarkDisabled(b *testing.B) {
+	logger := benchLogger(b, discardWriter{})
+	b.ReportAllocs()
+	for b.Loop() {
+		logger.Debug("request %s took %d ms", "/api", 12)
+	}
+}
+
+func BenchmarkInfo(b *testing.B) {
+	logger := benchLogger(b, discardWriter{})
+	b.ReportAllocs()
+	for b.Loop() {
+		logger.Info("request handled")
+	}
+}
+
+func BenchmarkInfof(b *testing.B) {
+	logger := benchLogger(b, discardWriter{})
+	b.ReportAllocs()
+	for b.Loop() {
+		logger.Info("request %s took %d ms", "/api", 12)
+	}
+}
+
+func BenchmarkInfoFields(b *testing.B) {
+	logger := benchLogger(b, discardWriter{})
+	withFields := logger.With("request", "/api", "took", 12)
+	b.ReportAllocs()
+	for b.Loop() {
+		withFields.Info("request handled")
+	}
+}
+
+func BenchmarkConsole(b *testing.B) {
+	logger := benchLogger(b, discardConsole())
+	withFields := logger.With("request", "/api", "took", 12)
+	b.ReportAllocs()
+	for b.Loop() {
+		withFields.Info("request handled")
+	}
+}
+
+func BenchmarkIsError(b *testing.B) {
+	logger := benchLogger(b, discardWriter{})
+	err := io.ErrUnexpectedEOF
+	b.ReportAllocs()
+	for b.Loop() {
+		logger.IsError(err)
+	}
+}
+
+// the same lines through log/slog, for comparison
+
+func BenchmarkSlogDisabled(b *testing.B) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	b.ReportAllocs()
+	for b.Loop() {
+		logger.Debug("request handled", "request", "/api", "took", 12)
+	}
+}
+
+func BenchmarkSlogInfo(b *testing.B) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	b.ReportAllocs()
+	for b.Loop() {
+		logger.Info("request handled")
+	}
+}
+
+func BenchmarkSlogInfoFields(b *testing.B) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil)).With("request", "/api", "took", 12)
+	b.ReportAllocs()
+	for b.Loop() {
+		logger.Info("request handled")
+	}
+}
+
+func BenchmarkSlogHandler(b *testing.B) {
+	benchLogger(b, discardWriter{})
+	logger := slog.New(NewSlogHandler("bench"))
+	ctx := context.Background()
+	b.ReportAllocs()
+	for b.Loop() {
+		logger.InfoContext(ctx, "request handled")
+	}
+}
+
+// TestAllocations keeps the hot paths from allocating more than they do now
+func TestAllocations(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector makes sync.Pool drop items")
+	}
+	assert := assert.New(t)
+
+	logger := benchLogger(t, discardWriter{})
+	withFields := logger.With("request", "/api", "took", 12)
+	path := "/api"
+	slogger := slog.New(NewSlogHandler("bench"))
+
+	for _, tt := range []struct {
+		name   string
+		max    float64
+		logged func()
+	}{
+		{"disabled", 0, func() { logger.Debug("request handled") }},
+		{"disabled with args", 0, func() { logger.Debug("request %s took %d ms", "/api", 12) }},
+		{"info", 0, func() { logger.Info("request handled") }},
+		{"info with fields", 0, func() { withFields.Info("request handled") }},
+		{"infof", 1, func() { logger.Info("request %s took %d ms", path, 12) }},
+		{"slog handler", 0, func() { slogger.Info("request handled") }},
+	} {
+		assert.LessOrEqual(testing.AllocsPerRun(100, tt.logged), tt.max, tt.name)
+	}
+
+	benchLogger(t, discardConsole())
+	assert.LessOrEqual(testing.AllocsPerRun(100, func() { logger.Info("request handled") }), 0.0, "console")
+}
@@ -0,0 +1,57 @@
+package logmanager
+
+import (
+	"strconv"
+	"sync"
+)
+
+// buffer is a reusable byte slice for formatting lines without allocating, see getBuffer
+type buffer []byte
+
+// maxPooledBuffer keeps the odd huge line from pinning its buffer in the pool forever
+const maxPooledBuffer = 64 << 10
+
+var bufferPool = sync.Pool{New: func() any {
+	b := make(buffer, 0, 1024)
+	return &b
+}}
+
+// getBuffer returns an empty buffer from the pool, return it with free once it's no longer used
+func getBuffer() *buffer {
+	return bufferPool.Get().(*buffer)
+}
+
+func (b *buffer) free() {
+	if cap(*b) > maxPooledBuffer {
+		return
+	}
+	*b = (*b)[:0]
+	bufferPool.Put(b)
+}
+
+// appendFieldsText appends fields as " key=value key2=value2", like formatFields
+func appendFieldsText(b []byte, fields []Field) []byte {
+	for _, field := range fields {
+		b = append(b, ' ')
+		b = append(b, field.Key...)
+		b = append(b, '=')
+		b = appendFieldValue(b, field.Value)
+	}
+	return b
+}
+
+// appendFieldValue appends the value as Field.String formats it, without converting common types to a string first
+func appendFieldValue(b []byte, v any) []byte {
+	switch v := v.(type) {
+	case int:
+		return strconv.AppendInt(b, int64(v), 10)
+	case int64:
+		return strconv.AppendInt(b, v, 10)
+	case uint64:
+		return strconv.AppendUint(b, v, 10)
+	case bool:
+		return strconv.AppendBool(b, v)
+	default:
+		return append(b, quoteFieldValue(fieldValueString(v))...)
+	}
+}
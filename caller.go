@@ -0,0 +1,82 @@
+package logmanager
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// maxHelperDepth is how many frames are looked at to get past helper functions
+const maxHelperDepth = 32
+
+var (
+	helpers     = map[string]bool{} // function name -> marked as helper
+	helpersLock sync.RWMutex
+	// helperCount is the number of helpers, so the common case of none skips the lock
+	helperCount int32
+)
+
+// Helper marks the calling function as a logging helper, like testing.T.Helper. when a line is logged
+// the file and line of the helper are skipped over in favor of the code calling it:
+//
+//	func logRequest(log *logmanager.Logger, r *http.Request) {
+//		logmanager.Helper()
+//		log.Info("%s %s", r.Method, r.URL)
+//	}
+//
+// helpers are skipped by the logging methods of Logger, including IsError and Recover, not by slog
+func Helper() {
+	var pcs [1]uintptr
+	runtime.Callers(2, pcs[:])
+	function := lookupCaller(pcs[0]).function
+
+	helpersLock.RLock()
+	marked := helpers[function]
+	helpersLock.RUnlock()
+	if marked {
+		return
+	}
+
+	helpersLock.Lock()
+	defer helpersLock.Unlock()
+	if !helpers[function] {
+		helpers[function] = true
+		atomic.AddInt32(&helperCount, 1)
+	}
+}
+
+func isHelper(function string) bool {
+	if atomic.LoadInt32(&helperCount) == 0 {
+		return false
+	}
+	helpersLock.RLock()
+	defer helpersLock.RUnlock()
+	return helpers[function]
+}
+
+// WithCallerSkip returns a logger for the same module and fields that attributes lines to the code n frames
+// further up the stack, for wrappers that can't use Helper, e.g. because they are generated
+func (l *Logger) WithCallerSkip(n int) Logger {
+	logger := *l
+	logger.callerSkip += n
+	return logger
+}
+
+// callerPC returns the program counter of the call site skip frames above the function calling callerPC,
+// with the logger's caller skip and any helpers skipped as well. 0 if there is none
+func (l *Logger) callerPC(skip int) uintptr {
+	skip += 2 + l.callerSkip
+	if atomic.LoadInt32(&helperCount) == 0 {
+		var pcs [1]uintptr
+		runtime.Callers(skip, pcs[:])
+		return pcs[0]
+	}
+
+	var pcs [maxHelperDepth]uintptr
+	for _, pc := range pcs[:runtime.Callers(skip, pcs[:])] {
+		if !isHelper(lookupCaller(pc).function) {
+			return pc
+		}
+	}
+	return 0
+}
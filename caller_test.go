@@ -0,0 +1,90 @@
+package logmanager
+
+import (
+	"errors"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func logThroughHelper(logger *Logger, message string) {
+	Helper()
+	logger.Info("%s", message)
+}
+
+func logThroughNestedHelper(logger *Logger, message string) {
+	Helper()
+	logThroughHelper(logger, message)
+}
+
+func isErrorThroughHelper(logger *Logger, err error) bool {
+	Helper()
+	return logger.IsError(err)
+}
+
+func printCallerThroughHelper(logger *Logger) {
+	Helper()
+	logger.PrintCaller(0)
+}
+
+func logThroughWrapper(logger *Logger, message string) {
+	wrapped := logger.WithCallerSkip(1)
+	wrapped.Log(Warning, "%s", message)
+}
+
+// line returns the line it's called from
+func line() int {
+	_, _, line, _ := runtime.Caller(1)
+	return line
+}
+
+func TestCallerAttribution(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	w := captureWriters(t)
+	logger := GetLogger("caller")
+	logger.SetLogLevel(Trace)
+
+	var want []int
+	logger.Log(Info, "direct")
+	want = append(want, line()-1)
+	logThroughHelper(&logger, "helper")
+	want = append(want, line()-1)
+	logThroughNestedHelper(&logger, "nested helper")
+	want = append(want, line()-1)
+	logThroughWrapper(&logger, "wrapper")
+	want = append(want, line()-1)
+	isErrorThroughHelper(&logger, errors.New("broken"))
+	want = append(want, line()-1)
+	logger.CheckErr(func() error { return errors.New("check") })
+	want = append(want, line()-1)
+	skipped := logger.WithCallerSkip(1)
+	withFields := skipped.With("request", 1)
+	printCallerThroughHelper(&logger)
+	want = append(want, line()-1)
+	logThroughWrapper(&withFields, "skip adds up")
+
+	lines := w.Lines()
+	require.Len(lines, len(want)+1)
+	for i, l := range lines[:len(want)] {
+		assert.Equal("caller_test.go", l.filename, l.message)
+		assert.Equal(want[i], l.line, l.message)
+		assert.Equal("github.com/axiomhq/logmanager.TestCallerAttribution", l.function, l.message)
+	}
+	assert.Contains(lines[6].message, "caller_test.go:L"+strconv.Itoa(want[6])+" ", "PrintCaller skips helpers")
+
+	r := Record{Stack: lines[4].stack}
+	require.NotEmpty(r.Stack)
+	frame, _ := runtime.CallersFrames(r.Stack[:1]).Next()
+	assert.Equal("github.com/axiomhq/logmanager.TestCallerAttribution", frame.Function, "the stack starts past the helper")
+
+	// the wrapper's skip and the logger's add up to the function calling the test
+	skippedLine := lines[7]
+	assert.Equal([]Field{{"request", 1}}, skippedLine.fields)
+	assert.Equal("testing.tRunner", skippedLine.function, "skips add up")
+	assert.Equal("testing.go", skippedLine.filename)
+}
@@ -0,0 +1,84 @@
+package logmanager
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// maxCauses limits how much of an error tree is walked, in case an error unwraps to itself
+const maxCauses = 64
+
+// ErrorCause is an error in the chain or tree of an error logged with IsError or Recover, see Record.Causes
+type ErrorCause struct {
+	// Depth is 0 for the logged error, 1 for the errors it wraps, 2 for the errors they wrap and so on
+	Depth   int    `json:"depth"`
+	Type    string `json:"type"` // the Go type, e.g. "*fs.PathError"
+	Message string `json:"message"`
+	// Stack is the stack of a LoggedError, where the error was returned by Logger.Error
+	Stack []uintptr `json:"-"`
+}
+
+// errorCauses walks err and everything it wraps, with Unwrap() error as well as Unwrap() []error as used by
+// errors.Join, depth first. A LoggedError isn't listed itself, its stack is added to the error it wraps
+func errorCauses(err error) []ErrorCause {
+	var causes []ErrorCause
+	var walk func(err error, depth int, stack []uintptr)
+	walk = func(err error, depth int, stack []uintptr) {
+		if err == nil || len(causes) == maxCauses {
+			return
+		}
+
+		if logged, ok := err.(*LoggedError); ok {
+			walk(logged.Err, depth, logged.Stack)
+			return
+		}
+
+		causes = append(causes, ErrorCause{Depth: depth, Type: fmt.Sprintf("%T", err), Message: err.Error(), Stack: stack})
+		switch wrapper := err.(type) {
+		case interface{ Unwrap() error }:
+			walk(wrapper.Unwrap(), depth+1, nil)
+		case interface{ Unwrap() []error }:
+			for _, wrapped := range wrapper.Unwrap() {
+				walk(wrapped, depth+1, nil)
+			}
+		}
+	}
+	walk(err, 0, nil)
+	return causes
+}
+
+// CauseTree formats the causes of the record, one per line indented by depth with their type, e.g.
+//
+//	Caused by:
+//	  *fmt.wrapError: loading config: open app.yaml: no such file or directory
+//	    *fs.PathError: open app.yaml: no such file or directory
+//	      syscall.Errno: no such file or directory
+//
+// it's empty unless the logged error wraps other errors. stacks of LoggedErrors are added below them,
+// unless it's the stack of the record
+func (r *Record) CauseTree() string {
+	if len(r.Causes) < 2 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Caused by:\n")
+	for _, cause := range r.Causes {
+		indent := strings.Repeat("  ", cause.Depth+1)
+		sb.WriteString(indent)
+		sb.WriteString(cause.Type)
+		sb.WriteString(": ")
+		sb.WriteString(cause.Message)
+		sb.WriteByte('\n')
+
+		if len(cause.Stack) == 0 || slices.Equal(cause.Stack, r.Stack) {
+			continue
+		}
+		for line := range strings.Lines(formatStack(cause.Stack, maxCallers)) {
+			sb.WriteString(indent)
+			sb.WriteString(line)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,100 @@
+package logmanager
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// selfWrapping unwraps to itself, so walking it never ends
+type selfWrapping struct{}
+
+func (e *selfWrapping) Error() string { return "again" }
+func (e *selfWrapping) Unwrap() error { return e }
+
+func TestErrorCauses(t *testing.T) {
+	assert := assert.New(t)
+
+	pathErr := &fs.PathError{Op: "open", Path: "app.yaml", Err: fs.ErrNotExist}
+	err := errors.Join(fmt.Errorf("loading config: %w", pathErr), errors.New("closing"))
+
+	causes := errorCauses(err)
+	assert.Equal([]ErrorCause{
+		{Depth: 0, Type: "*errors.joinError", Message: "loading config: open app.yaml: file does not exist\nclosing"},
+		{Depth: 1, Type: "*fmt.wrapError", Message: "loading config: open app.yaml: file does not exist"},
+		{Depth: 2, Type: "*fs.PathError", Message: "open app.yaml: file does not exist"},
+		{Depth: 3, Type: "*errors.errorString", Message: "file does not exist"},
+		{Depth: 1, Type: "*errors.errorString", Message: "closing"},
+	}, causes)
+
+	assert.Len(errorCauses(&selfWrapping{}), maxCauses)
+	assert.Nil(errorCauses(nil))
+
+	logger := GetLogger("causes")
+	logged := logger.Error("reading: %w", pathErr)
+	causes = errorCauses(fmt.Errorf("startup: %w", logged))
+	if assert.Len(causes, 4) {
+		assert.Equal("*fmt.wrapError", causes[1].Type, "the LoggedError itself isn't listed")
+		assert.Equal(logged.(*LoggedError).Stack, causes[1].Stack)
+		assert.Empty(causes[0].Stack)
+	}
+
+	r := Record{Causes: causes}
+	tree := r.CauseTree()
+	assert.True(strings.HasPrefix(tree, "Caused by:\n"+
+		"  *fmt.wrapError: startup: reading: open app.yaml: file does not exist\n"+
+		"    *fmt.wrapError: reading: open app.yaml: file does not exist\n"+
+		"      github.com/axiomhq/logmanager/causes_test.go:"), tree)
+	assert.Contains(tree, "@ github.com/axiomhq/logmanager.TestErrorCauses")
+	assert.True(strings.HasSuffix(tree, "      *fs.PathError: open app.yaml: file does not exist\n"+
+		"        *errors.errorString: file does not exist\n"), tree)
+
+	r.Stack = causes[1].Stack
+	assert.NotContains(r.CauseTree(), "@ ", "the stack of the record isn't repeated")
+	r.Causes = causes[:1]
+	assert.Empty(r.CauseTree())
+}
+
+func TestIsErrorCauses(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	w := captureWriters(t)
+	legacy := &legacyCaptureWriter{}
+	var buf bytes.Buffer
+	AddWriter(legacy)
+	AddWriter(NewSlogWriter(slog.NewJSONHandler(&buf, nil)))
+
+	logger := GetLogger("causes")
+	err := fmt.Errorf("connecting: %w", errors.New("dial postgres://app:hunter2@db failed"))
+	logger.IsError(err)
+	assert.Error(logger.Recover(err))
+
+	lines := w.Lines()
+	require.Len(lines, 2)
+	for _, line := range lines {
+		assert.Equal([]ErrorCause{
+			{Depth: 0, Type: "*fmt.wrapError", Message: "connecting: dial postgres://app:[REDACTED]@db failed"},
+			{Depth: 1, Type: "*errors.errorString", Message: "dial postgres://app:[REDACTED]@db failed"},
+		}, line.causes)
+	}
+
+	require.Len(legacy.messages, 2)
+	assert.Contains(legacy.messages[0], "Detected error: connecting: dial postgres://app:[REDACTED]@db failed\nCaused by:\n"+
+		"  *fmt.wrapError: connecting: dial postgres://app:[REDACTED]@db failed\n"+
+		"    *errors.errorString: dial postgres://app:[REDACTED]@db failed\n")
+
+	var logged struct {
+		Causes []ErrorCause `json:"causes"`
+	}
+	require.NoError(json.NewDecoder(&buf).Decode(&logged))
+	assert.Equal(lines[0].causes, logged.Causes)
+}
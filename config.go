@@ -0,0 +1,170 @@
+package logmanager
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variables read by ConfigFromEnv
+const (
+	EnvSpec          = "LOGMANAGER_SPEC"
+	EnvLegacySpec    = "AXIOM_DEBUG" // used if LOGMANAGER_SPEC isn't set
+	EnvConsole       = "LOGMANAGER_CONSOLE"
+	EnvDiskPath      = "LOGMANAGER_DISK_PATH"
+	EnvDiskRotate    = "LOGMANAGER_DISK_ROTATE"
+	EnvDiskMaxFiles  = "LOGMANAGER_DISK_MAX_FILES"
+	EnvSyslog        = "LOGMANAGER_SYSLOG"
+	EnvColoredOutput = "LOGMANAGER_COLORED_OUTPUT"
+)
+
+// defaults for the disk writer when it is configured from the environment
+const (
+	defaultRotateDuration = 24 * time.Hour
+	defaultMaxLogFiles    = 7
+)
+
+// Config describes the logging setup of a process, see ConfigFromEnv
+type Config struct {
+	Spec string
+
+	// Console enables the ConsoleWriter
+	Console bool
+
+	// DiskPath enables the DiskWriter writing to the given path
+	DiskPath string
+	Disk     DiskWriterConfig
+
+	// Syslog enables the SyslogWriter, an empty SyslogNetwork means the local syslog daemon
+	Syslog        bool
+	SyslogNetwork string
+	SyslogAddress string
+}
+
+// specFromEnv returns LOGMANAGER_SPEC, or AXIOM_DEBUG if that isn't set
+func specFromEnv() string {
+	if spec, ok := os.LookupEnv(EnvSpec); ok {
+		return spec
+	}
+	return os.Getenv(EnvLegacySpec)
+}
+
+// ConfigFromEnv reads the configuration from the environment:
+//
+//	LOGMANAGER_SPEC            module levels, e.g. "foo.bar=Debug:foo=Trace:Info" (AXIOM_DEBUG is used if unset)
+//	LOGMANAGER_CONSOLE         set to false to turn off the console output
+//	LOGMANAGER_DISK_PATH       log to this file as well
+//	LOGMANAGER_DISK_ROTATE     how often the log file is rotated, defaults to 24h
+//	LOGMANAGER_DISK_MAX_FILES  how many log files are kept, defaults to 7
+//	LOGMANAGER_SYSLOG          log to syslog as well, "local" for the local daemon or e.g. "udp://10.0.0.1:514"
+//	LOGMANAGER_COLORED_OUTPUT  1 or 0 to force colors on the console on or off (AXIOM_COLORED_OUTPUT is used if unset)
+//
+// the package applies this configuration on start up, so a binary can be configured without any code changes.
+// as much of the configuration as possible is returned, even if some variables are invalid
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Spec:    specFromEnv(),
+		Console: true,
+		Disk: DiskWriterConfig{
+			RotateDuration:  defaultRotateDuration,
+			MaximumLogFiles: defaultMaxLogFiles,
+		},
+	}
+
+	var errs []string
+	if v := os.Getenv(EnvConsole); v != "" {
+		console, err := strconv.ParseBool(v)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", EnvConsole, err))
+		} else {
+			cfg.Console = console
+		}
+	}
+
+	cfg.DiskPath = os.Getenv(EnvDiskPath)
+	if v := os.Getenv(EnvDiskRotate); v != "" {
+		rotate, err := time.ParseDuration(v)
+		if err != nil || rotate <= 0 {
+			errs = append(errs, fmt.Sprintf("%s: invalid duration %q", EnvDiskRotate, v))
+		} else {
+			cfg.Disk.RotateDuration = rotate
+		}
+	}
+	if v := os.Getenv(EnvDiskMaxFiles); v != "" {
+		maxFiles, err := strconv.Atoi(v)
+		if err != nil || maxFiles < 1 {
+			errs = append(errs, fmt.Sprintf("%s: invalid number of files %q", EnvDiskMaxFiles, v))
+		} else {
+			cfg.Disk.MaximumLogFiles = maxFiles
+		}
+	}
+
+	if v := os.Getenv(EnvSyslog); v != "" {
+		cfg.Syslog = true
+		if v != "local" {
+			network, address, found := strings.Cut(v, "://")
+			if !found || network == "" || address == "" {
+				errs = append(errs, fmt.Sprintf(`%s: expected "local" or network://address, got %q`, EnvSyslog, v))
+				cfg.Syslog = false
+			}
+			cfg.SyslogNetwork, cfg.SyslogAddress = network, address
+		}
+	}
+
+	if len(errs) > 0 {
+		return cfg, fmt.Errorf("invalid logging configuration: %s", strings.Join(errs, ", "))
+	}
+	return cfg, nil
+}
+
+// Writers creates the writers described by the configuration, writers that can't be created
+// are left out and reported in the returned error. If none of them could be created the console
+// is used instead, so the logs don't go nowhere
+func (c Config) Writers() ([]Writer, error) {
+	var writers []Writer
+	if c.Console {
+		writers = append(writers, NewConsoleWriter())
+	}
+
+	if c.DiskPath != "" {
+		writers = append(writers, NewDiskWriter(c.DiskPath, c.Disk))
+	}
+
+	if c.Syslog {
+		writer, err := NewSyslogWriter(c.SyslogNetwork, c.SyslogAddress)
+		if err != nil {
+			err = fmt.Errorf("could not connect to syslog: %w", err)
+			if len(writers) == 0 {
+				return []Writer{NewConsoleWriter()}, fmt.Errorf("%w, logging to the console instead", err)
+			}
+			return writers, err
+		}
+		writers = append(writers, writer)
+	}
+
+	return writers, nil
+}
+
+// isDefault reports whether the configuration only asks for the console, which is what is used without any configuration
+func (c Config) isDefault() bool {
+	return c.Console && c.DiskPath == "" && !c.Syslog
+}
+
+func init() {
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		println("Warning:", err.Error())
+	}
+
+	if cfg.isDefault() {
+		return
+	}
+
+	writers, err := cfg.Writers()
+	if err != nil {
+		println("Warning:", err.Error())
+	}
+	ReplaceWriters(writers...)
+}
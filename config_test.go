@@ -0,0 +1,95 @@
+package logmanager
+
+import (
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, env := range []string{EnvSpec, EnvLegacySpec, EnvConsole, EnvDiskPath, EnvDiskRotate, EnvDiskMaxFiles, EnvSyslog} {
+		t.Setenv(env, "")
+	}
+
+	cfg, err := ConfigFromEnv()
+	assert.NoError(err)
+	assert.True(cfg.isDefault())
+
+	t.Setenv(EnvLegacySpec, "foo=Trace")
+	cfg, err = ConfigFromEnv()
+	assert.NoError(err)
+	assert.Equal("", cfg.Spec, "an empty LOGMANAGER_SPEC still takes precedence")
+
+	t.Setenv(EnvSpec, "Debug")
+	t.Setenv(EnvConsole, "false")
+	t.Setenv(EnvDiskPath, "/var/log/app.log")
+	t.Setenv(EnvDiskRotate, "1h")
+	t.Setenv(EnvDiskMaxFiles, "3")
+	t.Setenv(EnvSyslog, "udp://127.0.0.1:514")
+
+	cfg, err = ConfigFromEnv()
+	assert.NoError(err)
+	assert.Equal(Config{
+		Spec:          "Debug",
+		Console:       false,
+		DiskPath:      "/var/log/app.log",
+		Disk:          DiskWriterConfig{RotateDuration: time.Hour, MaximumLogFiles: 3},
+		Syslog:        true,
+		SyslogNetwork: "udp",
+		SyslogAddress: "127.0.0.1:514",
+	}, cfg)
+
+	t.Setenv(EnvSyslog, "local")
+	cfg, err = ConfigFromEnv()
+	assert.NoError(err)
+	assert.True(cfg.Syslog)
+	assert.Empty(cfg.SyslogNetwork)
+
+	t.Setenv(EnvConsole, "nope")
+	t.Setenv(EnvDiskRotate, "-1h")
+	t.Setenv(EnvDiskMaxFiles, "none")
+	t.Setenv(EnvSyslog, "127.0.0.1:514")
+	cfg, err = ConfigFromEnv()
+	assert.ErrorContains(err, EnvConsole)
+	assert.ErrorContains(err, EnvDiskRotate)
+	assert.ErrorContains(err, EnvDiskMaxFiles)
+	assert.ErrorContains(err, EnvSyslog)
+	assert.True(cfg.Console, "invalid values should leave the defaults")
+	assert.Equal(defaultRotateDuration, cfg.Disk.RotateDuration)
+	assert.Equal(defaultMaxLogFiles, cfg.Disk.MaximumLogFiles)
+	assert.False(cfg.Syslog)
+}
+
+func TestConfigWriters(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	cfg := Config{
+		Console:       true,
+		DiskPath:      path.Join(t.TempDir(), "app.log"),
+		Disk:          DiskWriterConfig{RotateDuration: time.Hour, MaximumLogFiles: 1},
+		Syslog:        true,
+		SyslogNetwork: "udp",
+		SyslogAddress: "127.0.0.1:514",
+	}
+
+	writers, err := cfg.Writers()
+	require.NoError(err)
+	require.Len(writers, 3)
+	assert.IsType(&ConsoleWriter{}, writers[0])
+	assert.IsType(&DiskWriter{}, writers[1])
+	assert.IsType(&SyslogWriter{}, writers[2])
+	assert.NoError(writers[1].(*DiskWriter).Close())
+	assert.NoError(writers[2].(*SyslogWriter).Close())
+
+	cfg = Config{Syslog: true, SyslogNetwork: "nope", SyslogAddress: "127.0.0.1:514"}
+	writers, err = cfg.Writers()
+	assert.ErrorContains(err, "logging to the console instead")
+	require.Len(writers, 1)
+	assert.IsType(&ConsoleWriter{}, writers[0], "without any other writer the console is used")
+}
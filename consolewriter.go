@@ -1,11 +1,12 @@
 package logmanager
 
 import (
-	"fmt"
 	"hash/crc32"
+	"io"
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,7 +15,11 @@ import (
 
 func init() {
 	// the library detects whether the terminal supports color, override with this
-	switch os.Getenv("AXIOM_COLORED_OUTPUT") {
+	coloredOutput, ok := os.LookupEnv(EnvColoredOutput)
+	if !ok {
+		coloredOutput = os.Getenv("AXIOM_COLORED_OUTPUT")
+	}
+	switch coloredOutput {
 	case "1":
 		color.NoColor = false
 	case "0":
@@ -36,8 +41,9 @@ func init() {
 
 // colors
 var (
-	processName  string
-	moduleColors = []func(string, ...any) string{
+	processName   string
+	fieldKeyColor = color.New(color.Faint).SprintFunc()
+	moduleColors  = []func(string, ...any) string{
 		color.New(color.FgHiGreen, color.Faint).SprintfFunc(),
 		color.New(color.FgHiGreen).SprintfFunc(),
 		color.New(color.FgGreen).SprintfFunc(),
@@ -64,11 +70,28 @@ func getColor(str string) func(string, ...any) string {
 
 // ConsoleWriter will write out to a console
 type ConsoleWriter struct {
+	minLevel Level
+	out      io.Writer // os.Stdout if nil
 }
 
 // NewConsoleWriter ...
 func NewConsoleWriter() *ConsoleWriter {
-	return &ConsoleWriter{}
+	return &ConsoleWriter{out: os.Stdout}
+}
+
+// SetMinLevel sets the lowest level written to the console, by default everything is
+func (w *ConsoleWriter) SetMinLevel(level Level) {
+	w.minLevel.Set(level)
+}
+
+// MinLevel returns the lowest level written to the console
+func (w *ConsoleWriter) MinLevel() Level {
+	return w.minLevel.Get()
+}
+
+// Enabled ...
+func (w *ConsoleWriter) Enabled(level Level, _ string) bool {
+	return level >= w.minLevel.Get()
 }
 
 // BuildTheme ...
@@ -88,9 +111,51 @@ func (w *ConsoleWriter) BuildTheme(module string) ColorTheme {
 }
 
 // Log ...
-func (w *ConsoleWriter) Log(level Level, theme ColorTheme, _ /*module*/, filename string, line int, timestamp time.Time, message string) {
-	ts := timestamp.In(time.UTC).Format("15:04:05.00")
-	filename = filepath.Base(filename)
+func (w *ConsoleWriter) Log(level Level, theme ColorTheme, module, filename string, line int, timestamp time.Time, message string) {
+	w.WriteRecord(theme, &Record{Level: level, Time: timestamp, Module: module, File: filename, Line: line, Message: message})
+}
 
-	fmt.Printf("[%s] %s %s@%s %s:%d %s\n", ts, theme.Levels[level], processName, theme.Module, filename, line, message)
+// WriteRecord ...
+func (w *ConsoleWriter) WriteRecord(theme ColorTheme, r *Record) {
+	if r.Level < w.minLevel.Get() {
+		return
+	}
+
+	buf := getBuffer()
+	defer buf.free()
+
+	// [15:04:05.00] level process@module file.go:12 message key=value
+	b := append(*buf, '[')
+	b = r.Time.In(time.UTC).AppendFormat(b, "15:04:05.00")
+	b = append(b, "] "...)
+	b = append(b, theme.Levels[r.Level]...)
+	b = append(b, ' ')
+	b = append(b, processName...)
+	b = append(b, '@')
+	b = append(b, theme.Module...)
+	b = append(b, ' ')
+	b = append(b, filepath.Base(r.File)...)
+	b = append(b, ':')
+	b = strconv.AppendInt(b, int64(r.Line), 10)
+	b = append(b, ' ')
+	b = append(b, r.StackTrace()...)
+	b = append(b, r.Message...)
+	b = append(b, r.CauseTree()...)
+	if color.NoColor {
+		b = appendFieldsText(b, r.Fields)
+	} else {
+		for _, field := range r.Fields {
+			b = append(b, ' ')
+			b = append(b, fieldKeyColor(field.Key+"=")...)
+			b = appendFieldValue(b, field.Value)
+		}
+	}
+	b = append(b, '\n')
+	*buf = b
+
+	out := w.out
+	if out == nil {
+		out = os.Stdout
+	}
+	_, _ = out.Write(b)
 }
@@ -0,0 +1,130 @@
+package logmanager
+
+import (
+	"context"
+	"slices"
+)
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx that carries logger, the fields attached to it
+// are added to every line logged with the *Ctx methods further down the call stack
+//
+//	ctx = logmanager.NewContext(ctx, log.With("request", reqID))
+//	...
+//	dbLog.InfoCtx(ctx, "query took %s", took) // logs request=<reqID>
+func NewContext(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger.With())
+}
+
+// FromContext returns the logger stored in ctx by NewContext
+func FromContext(ctx context.Context) (Logger, bool) {
+	stored, ok := ctx.Value(contextKey{}).(Logger)
+	if !ok {
+		return Logger{}, false
+	}
+	return stored.With(), true
+}
+
+// WithContext returns a logger for the same module as l, with the fields of the
+// logger stored in ctx added in front of its own. The level stays the one of l
+func (l *Logger) WithContext(ctx context.Context) Logger {
+	derived := l.With()
+	stored, ok := ctx.Value(contextKey{}).(Logger)
+	if !ok || len(stored.fields) == 0 {
+		return derived
+	}
+
+	switch commonScope(l.scope, stored.scope) {
+	case stored.scope:
+		// l already has the fields of the stored logger
+	case l.scope:
+		// the stored logger has every field of l
+		derived.fields, derived.scope = slices.Clip(stored.fields), stored.scope
+	default:
+		derived.fields = l.contextFields(ctx)
+		derived.scope = &fieldScope{parent: stored.scope, n: len(derived.fields)}
+	}
+	return derived
+}
+
+// contextFields returns the fields of the logger stored in ctx followed by the fields of l. the fields l has in
+// common with the stored logger, because one was derived from the other with With, aren't repeated
+func (l *Logger) contextFields(ctx context.Context) []Field {
+	stored, ok := ctx.Value(contextKey{}).(Logger)
+	if !ok || len(stored.fields) == 0 {
+		return l.fields
+	}
+
+	common := commonScope(l.scope, stored.scope)
+	switch {
+	case common == stored.scope:
+		return l.fields
+	case common == nil:
+		return append(slices.Clip(stored.fields), l.fields...)
+	default:
+		return append(slices.Clip(stored.fields), l.fields[common.n:]...)
+	}
+}
+
+// commonScope returns the closest scope a and b were both derived from, nil if there is none
+func commonScope(a, b *fieldScope) *fieldScope {
+	for ; a != nil; a = a.parent {
+		for s := b; s != nil; s = s.parent {
+			if s == a {
+				return a
+			}
+		}
+	}
+	return nil
+}
+
+// Context aware logging methods, these add the fields of the logger stored in ctx
+
+// LogCtx ...
+func (l *Logger) LogCtx(ctx context.Context, level Level, message string, args ...any) {
+	l.log(ctx, nil, nil, level, message, args...)
+}
+
+// TraceCtx ...
+func (l *Logger) TraceCtx(ctx context.Context, message string, args ...any) {
+	l.log(ctx, nil, nil, Trace, message, args...)
+}
+
+// DebugCtx ...
+func (l *Logger) DebugCtx(ctx context.Context, message string, args ...any) {
+	l.log(ctx, nil, nil, Debug, message, args...)
+}
+
+// InfoCtx ...
+func (l *Logger) InfoCtx(ctx context.Context, message string, args ...any) {
+	l.log(ctx, nil, nil, Info, message, args...)
+}
+
+// WarnCtx ...
+func (l *Logger) WarnCtx(ctx context.Context, message string, args ...any) {
+	l.log(ctx, nil, nil, Warning, message, args...)
+}
+
+// CriticalCtx ...
+func (l *Logger) CriticalCtx(ctx context.Context, message string, args ...any) {
+	l.log(ctx, nil, nil, Critical, message, args...)
+}
+
+// ErrorCtx ...
+func (l *Logger) ErrorCtx(ctx context.Context, message string, args ...any) error {
+	return l.logError(ctx, Error, message, args...)
+}
+
+// FatalCtx is Fatal with the fields of the logger stored in ctx
+func (l *Logger) FatalCtx(ctx context.Context, message string, args ...any) {
+	l.log(ctx, nil, nil, Critical, message, args...)
+	exit()
+}
+
+// PanicCtx is Panic with the fields of the logger stored in ctx
+func (l *Logger) PanicCtx(ctx context.Context, message string, args ...any) {
+	err := l.logError(ctx, Critical, message, args...)
+	flushBeforeExit()
+	panic(err)
+}
@@ -0,0 +1,102 @@
+package logmanager
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContext(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	_, ok := FromContext(t.Context())
+	assert.False(ok)
+
+	reqLogger := GetLogger("context.http")
+	ctx := NewContext(t.Context(), reqLogger.With("request", 12))
+
+	ctxLogger, ok := FromContext(ctx)
+	require.True(ok)
+	assert.Equal("context.http", ctxLogger.name)
+	assert.Equal([]Field{{"request", 12}}, ctxLogger.Fields())
+
+	dbLogger := GetLogger("context.db")
+	dbLogger.SetLogLevel(Warning)
+	dbLogger = dbLogger.With("table", "users")
+	w := captureWriters(t)
+
+	dbLogger.InfoCtx(ctx, "filtered out by the module level")
+	dbLogger.WarnCtx(ctx, "slow query")
+	_ = dbLogger.ErrorCtx(ctx, "failed")
+
+	lines := w.Lines()
+	require.Len(lines, 2)
+	assert.Equal("context.db", lines[0].module)
+	assert.Equal("context_test.go", lines[0].filename)
+	assert.Equal([]Field{{"request", 12}, {"table", "users"}}, lines[0].fields)
+	assert.Equal(Error, lines[1].level)
+
+	derived := dbLogger.WithContext(ctx)
+	assert.Equal([]Field{{"request", 12}, {"table", "users"}}, derived.Fields())
+	assert.Equal(Warning, derived.LogLevel())
+
+	// logging with the context's own logger doesn't duplicate its fields
+	w = captureWriters(t)
+	ctxLogger.InfoCtx(ctx, "no duplicates")
+	userLogger := ctxLogger.With("user", 2)
+	userLogger.InfoCtx(ctx, "no duplicates after With")
+	fromDerived := derived.WithContext(ctx)
+	fromDerived.WarnCtx(ctx, "no duplicates after WithContext")
+	reqLogger.InfoCtx(ctx, "fields of the context")
+
+	// a logger from an outer context only adds its own fields to those of the inner one
+	inner := NewContext(ctx, userLogger.With("tenant", "acme"))
+	ctxLogger.InfoCtx(inner, "derived from the outer context")
+	outerUser := ctxLogger.With("user", 3)
+	outerUser.InfoCtx(inner, "derived from the outer context with fields")
+
+	lines = w.Lines()
+	require.Len(lines, 6)
+	assert.Equal([]Field{{"request", 12}}, lines[0].fields)
+	assert.Equal([]Field{{"request", 12}, {"user", 2}}, lines[1].fields)
+	assert.Equal([]Field{{"request", 12}, {"table", "users"}}, lines[2].fields)
+	assert.Equal([]Field{{"request", 12}}, lines[3].fields)
+	assert.Equal([]Field{{"request", 12}, {"user", 2}, {"tenant", "acme"}}, lines[4].fields)
+	assert.Equal([]Field{{"request", 12}, {"user", 2}, {"tenant", "acme"}, {"user", 3}}, lines[5].fields)
+
+	// the logger that was stored, and one stored by a middleware further down, don't repeat their own fields
+	w = captureWriters(t)
+	stored := reqLogger.With("request", 1)
+	ctx = NewContext(t.Context(), stored)
+	stored.InfoCtx(ctx, "stored logger")
+	middleware, ok := FromContext(ctx)
+	require.True(ok)
+	withUser := middleware.With("user", 2)
+	userCtx := NewContext(ctx, withUser)
+	withUser.InfoCtx(userCtx, "stored by the middleware")
+	middleware.InfoCtx(userCtx, "outer logger")
+	derived = withUser.WithContext(userCtx)
+	assert.Equal([]Field{{"request", 1}, {"user", 2}}, derived.Fields())
+	derived = stored.WithContext(userCtx)
+	assert.Equal([]Field{{"request", 1}, {"user", 2}}, derived.Fields())
+
+	lines = w.Lines()
+	require.Len(lines, 3)
+	assert.Equal([]Field{{"request", 1}}, lines[0].fields)
+	assert.Equal([]Field{{"request", 1}, {"user", 2}}, lines[1].fields)
+	assert.Equal([]Field{{"request", 1}, {"user", 2}}, lines[2].fields)
+}
+
+func TestSlogHandlerContext(t *testing.T) {
+	h := NewSlogHandler("context.slog")
+	w := captureWriters(t)
+
+	reqLogger := GetLogger("context.http")
+	ctx := NewContext(t.Context(), reqLogger.With("request", 12))
+	slog.New(h).InfoContext(ctx, "hello", "user", "bob")
+
+	assert.Equal(t, []Field{{"request", 12}, {"user", "bob"}}, w.Lines()[0].fields)
+}
@@ -0,0 +1,142 @@
+package logmanager
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultDedupTimeout is how long repeated lines are collected before they are reported if Deduplicated
+// is given no timeout, the same as classic syslogd
+const DefaultDedupTimeout = 30 * time.Second
+
+// DedupWriter is a Writer that collapses consecutive identical lines, see Deduplicated
+type DedupWriter struct {
+	writer  RecordWriter
+	timeout time.Duration
+
+	mu        sync.Mutex
+	last      *Record // copy of the last line written, nil before the first
+	lastTheme ColorTheme
+	repeats   int
+	timer     *time.Timer
+	// generation is bumped whenever repeats are reported, so a timer that fires late knows it's stale
+	generation uint64
+}
+
+// Deduplicated wraps writer so lines with the same module, level and message as the line before are
+// counted instead of written. The count is written as "last message repeated N times" once a different
+// line arrives, or after timeout has passed since the first repeat (DefaultDedupTimeout if zero):
+//
+//	logmanager.ReplaceWriters(logmanager.Deduplicated(logmanager.NewConsoleWriter(), 0))
+func Deduplicated(writer Writer, timeout time.Duration) *DedupWriter {
+	if timeout <= 0 {
+		timeout = DefaultDedupTimeout
+	}
+	return &DedupWriter{writer: AsRecordWriter(writer), timeout: timeout}
+}
+
+// BuildTheme ...
+func (w *DedupWriter) BuildTheme(module string) ColorTheme {
+	return w.writer.BuildTheme(module)
+}
+
+// Enabled reports whether the wrapped writer is enabled for the level and module
+func (w *DedupWriter) Enabled(level Level, module string) bool {
+	return writerEnabled(w.writer, level, module)
+}
+
+// Log ...
+func (w *DedupWriter) Log(level Level, theme ColorTheme, module, filename string, line int, timestamp time.Time, message string) {
+	w.WriteRecord(theme, &Record{Level: level, Time: timestamp, Module: module, File: filename, Line: line, Message: message})
+}
+
+// WriteRecord ...
+func (w *DedupWriter) WriteRecord(theme ColorTheme, r *Record) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.last != nil && w.last.Module == r.Module && w.last.Level == r.Level && w.last.Message == r.Message {
+		w.repeats++
+		if w.repeats == 1 {
+			generation := w.generation
+			w.timer = time.AfterFunc(w.timeout, func() { w.timedOut(generation) })
+		}
+		return
+	}
+
+	w.writeRepeats()
+	w.writer.WriteRecord(theme, r)
+
+	// records must not be kept, so keep a copy
+	last := *r
+	last.Fields, last.Stack, last.Causes = slices.Clone(r.Fields), slices.Clone(r.Stack), slices.Clone(r.Causes)
+	w.last, w.lastTheme = &last, theme
+}
+
+// writeRepeats writes how often the last line was repeated if it was, w.mu must be held
+func (w *DedupWriter) writeRepeats() {
+	if w.repeats == 0 {
+		return
+	}
+
+	r := Record{
+		Level:    w.last.Level,
+		Time:     time.Now().UTC(),
+		Module:   w.last.Module,
+		PC:       w.last.PC,
+		File:     w.last.File,
+		Line:     w.last.Line,
+		Function: w.last.Function,
+		Message:  fmt.Sprintf("last message repeated %d times", w.repeats),
+	}
+	w.writer.WriteRecord(w.lastTheme, &r)
+
+	w.repeats = 0
+	w.generation++
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+}
+
+func (w *DedupWriter) timedOut(generation uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.generation == generation {
+		w.writeRepeats()
+	}
+}
+
+// Flush writes the count of repeated lines if there is one, and flushes the wrapped writer if it's a Flusher
+func (w *DedupWriter) Flush() error {
+	w.mu.Lock()
+	w.writeRepeats()
+	w.mu.Unlock()
+
+	if f, ok := w.Writer().(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Close writes the count of repeated lines if there is one, and closes the wrapped writer if it's a Closer
+func (w *DedupWriter) Close() error {
+	w.mu.Lock()
+	w.writeRepeats()
+	w.mu.Unlock()
+
+	if c, ok := w.Writer().(Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Writer returns the wrapped writer
+func (w *DedupWriter) Writer() Writer {
+	if legacy, ok := w.writer.(legacyWriter); ok {
+		return legacy.Writer
+	}
+	return w.writer
+}
@@ -0,0 +1,49 @@
+package logmanager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeduplicated(t *testing.T) {
+	assert := assert.New(t)
+
+	w := &captureWriter{}
+	dedup := Deduplicated(w, 20*time.Millisecond)
+	write := func(level Level, module, message string) {
+		dedup.WriteRecord(ColorTheme{}, &Record{Level: level, Module: module, File: "/src/retry.go", Line: 12, Message: message})
+	}
+	messages := func() (msgs []string) {
+		for _, line := range w.Lines() {
+			msgs = append(msgs, line.module+" "+line.level.String()+" "+line.message)
+		}
+		return msgs
+	}
+
+	write(Warning, "retry", "connection refused")
+	write(Warning, "retry", "connection refused")
+	write(Warning, "retry", "connection refused")
+	write(Error, "retry", "connection refused")
+	write(Error, "other", "connection refused")
+	write(Error, "other", "connection refused")
+	assert.Equal([]string{
+		"retry warn connection refused",
+		"retry warn last message repeated 2 times",
+		"retry error connection refused",
+		"other error connection refused",
+	}, messages())
+
+	assert.Eventually(func() bool { return len(w.Lines()) == 5 }, time.Second, time.Millisecond,
+		"repeats should be written after the timeout")
+	assert.Equal("other error last message repeated 1 times", messages()[4])
+	assert.Equal("retry.go", w.Lines()[4].filename, "the repeat count has the caller of the repeated line")
+
+	write(Error, "other", "connection refused")
+	assert.NoError(dedup.Flush())
+	assert.Len(w.Lines(), 6, "Flush writes pending repeats")
+	assert.NoError(dedup.Close())
+	assert.Len(w.Lines(), 6)
+	assert.Equal(Writer(w), dedup.Writer())
+}
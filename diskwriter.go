@@ -6,6 +6,8 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
+	"sync"
 	"time"
 )
 
@@ -18,13 +20,26 @@ type DiskWriterConfig struct {
 // DiskWriter ...
 type DiskWriter struct {
 	DiskWriterConfig
-	logpath string
+	logpath  string
+	minLevel Level
 
-	logbuf chan string
+	logbuf chan diskMessage
+	done   chan struct{} // closed once the file is written and closed
+
+	closeLock sync.RWMutex // held for writing while logbuf is closed
+	closed    bool
+	closeErr  error
+}
+
+// diskMessage is either a line to write or a flush request, which is acknowledged
+// by closing flushed once everything before it is written
+type diskMessage struct {
+	line    string
+	flushed chan struct{}
 }
 
 // rotateLogs will rotate the current logs and return the next rotation time
-func (w DiskWriter) rotateLogs() (time.Time, error) {
+func (w *DiskWriter) rotateLogs() (time.Time, error) {
 	if _, err := os.Stat(w.logpath); err != nil {
 		// no log to rotate yet
 		_ = os.MkdirAll(path.Dir(w.logpath), 0777)
@@ -89,52 +104,105 @@ func writeAll(writer io.Writer, buf []byte) error {
 
 // NewDiskWriter ...
 func NewDiskWriter(logpath string, config DiskWriterConfig) *DiskWriter {
-	w := &DiskWriter{config, logpath, make(chan string, 10000)}
-	go func() {
-		var err error
-		var file *os.File
-		rotateTime := time.Time{}
-
-		for logLine := range w.logbuf {
-			// rotate the logs if it has been longer than w.RotateDuration since last rotation
-			if time.Now().After(rotateTime) {
-				rotateTime, err = w.rotateLogs()
-				if err != nil {
-					println("Warning, could not create logfile:", err.Error())
-					continue
-				}
-				if file != nil {
-					file.Close()
-					file = nil
-				}
-			}
+	w := &DiskWriter{
+		DiskWriterConfig: config,
+		logpath:          logpath,
+		minLevel:         Info,
+		logbuf:           make(chan diskMessage, 10000),
+		done:             make(chan struct{}),
+	}
+	go w.writeloop()
+	return w
+}
+
+func (w *DiskWriter) writeloop() {
+	defer close(w.done)
 
-			if file == nil {
-				file, err = os.OpenFile(w.logpath, os.O_APPEND|os.O_WRONLY, os.ModeAppend)
-				if err != nil {
-					println("Warning, could not open logfile for appending:", err.Error())
-					continue
-				}
+	var err error
+	var file *os.File
+	rotateTime := time.Time{}
+
+	for msg := range w.logbuf {
+		if msg.flushed != nil {
+			close(msg.flushed)
+			continue
+		}
+
+		// rotate the logs if it has been longer than w.RotateDuration since last rotation
+		if time.Now().After(rotateTime) {
+			rotateTime, err = w.rotateLogs()
+			if err != nil {
+				println("Warning, could not create logfile:", err.Error())
+				continue
+			}
+			if file != nil {
+				file.Close()
+				file = nil
 			}
+		}
 
-			// write into the log file
-			err = writeAll(file, []byte(logLine))
+		if file == nil {
+			file, err = os.OpenFile(w.logpath, os.O_APPEND|os.O_WRONLY, os.ModeAppend)
 			if err != nil {
-				println("Warning, Error writing logfile:", err.Error())
+				println("Warning, could not open logfile for appending:", err.Error())
+				continue
 			}
 		}
 
-		if file != nil {
-			file.Close()
+		// write into the log file
+		err = writeAll(file, []byte(msg.line))
+		if err != nil {
+			println("Warning, Error writing logfile:", err.Error())
 		}
-	}()
+	}
 
-	return w
+	if file != nil {
+		w.closeErr = file.Close()
+	}
+}
+
+// Flush blocks until everything logged before it was called is written to the file
+func (w *DiskWriter) Flush() error {
+	w.closeLock.RLock()
+	if w.closed {
+		w.closeLock.RUnlock()
+		return nil
+	}
+	flushed := make(chan struct{})
+	w.logbuf <- diskMessage{flushed: flushed}
+	w.closeLock.RUnlock()
+
+	<-flushed
+	return nil
+}
+
+// Close will end the writer, it blocks until everything logged so far is written and the file is closed.
+// anything logged afterwards is dropped
+func (w *DiskWriter) Close() error {
+	w.closeLock.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.logbuf)
+	}
+	w.closeLock.Unlock()
+
+	<-w.done
+	return w.closeErr
+}
+
+// SetMinLevel sets the lowest level written to the file, Info by default
+func (w *DiskWriter) SetMinLevel(level Level) {
+	w.minLevel.Set(level)
 }
 
-// Close will end the writer
-func (w *DiskWriter) Close() {
-	close(w.logbuf)
+// MinLevel returns the lowest level written to the file
+func (w *DiskWriter) MinLevel() Level {
+	return w.minLevel.Get()
+}
+
+// Enabled ...
+func (w *DiskWriter) Enabled(level Level, _ string) bool {
+	return level >= w.minLevel.Get()
 }
 
 // BuildTheme ...
@@ -143,15 +211,45 @@ func (w *DiskWriter) BuildTheme(string) ColorTheme {
 }
 
 // Log ...
-func (w *DiskWriter) Log(level Level, _ ColorTheme, module, filename string, line int, timestamp time.Time, message string) {
-	if level <= Debug {
+func (w *DiskWriter) Log(level Level, theme ColorTheme, module, filename string, line int, timestamp time.Time, message string) {
+	w.WriteRecord(theme, &Record{Level: level, Time: timestamp, Module: module, File: filename, Line: line, Message: message})
+}
+
+// WriteRecord ...
+func (w *DiskWriter) WriteRecord(_ ColorTheme, r *Record) {
+	if r.Level < w.minLevel.Get() {
 		return
 	}
 
-	ts := timestamp.In(time.UTC).Format("15:04:05")
-	filename = filepath.Base(filename)
+	w.closeLock.RLock()
+	defer w.closeLock.RUnlock()
+	if w.closed {
+		return
+	}
+
+	buf := getBuffer()
+	defer buf.free()
+
+	// 15:04:05 level module file.go:12 message key=value
+	b := r.Time.In(time.UTC).AppendFormat(*buf, "15:04:05")
+	b = append(b, ' ')
+	b = append(b, r.Level.String()...)
+	b = append(b, ' ')
+	b = append(b, r.Module...)
+	b = append(b, ' ')
+	b = append(b, filepath.Base(r.File)...)
+	b = append(b, ':')
+	b = strconv.AppendInt(b, int64(r.Line), 10)
+	b = append(b, ' ')
+	b = append(b, r.StackTrace()...)
+	b = append(b, r.Message...)
+	b = append(b, r.CauseTree()...)
+	b = appendFieldsText(b, r.Fields)
+	b = append(b, '\n')
+	*buf = b
+
 	select {
-	case w.logbuf <- fmt.Sprintf("%s %s %s %s:%d %s\n", ts, level.String(), module, filename, line, message):
+	case w.logbuf <- diskMessage{line: string(b)}:
 	default:
 		println("WARNING: could not log to logfile, buffer full")
 	}
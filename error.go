@@ -0,0 +1,112 @@
+package logmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// LoggedError is the error returned by Logger.Error, it wraps the error that was logged together with
+// where it was logged. Error returns the same text as the wrapped error, errors.Is, errors.As and
+// errors.Unwrap see through it, and it's formatted with its stack with %+v
+type LoggedError struct {
+	// Err is the error passed as the first or last argument, otherwise the message formatted with fmt.Errorf,
+	// so %w wraps its argument
+	Err    error
+	Module string
+	Fields []Field
+	// Stack is where the error was logged first, an error wrapping a LoggedError reuses its stack
+	Stack []uintptr
+}
+
+// Error ...
+func (e *LoggedError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap ...
+func (e *LoggedError) Unwrap() error {
+	return e.Err
+}
+
+// StackTrace formats the stack of the error, see Record.StackTrace
+func (e *LoggedError) StackTrace() string {
+	r := Record{Stack: e.Stack}
+	return r.StackTrace()
+}
+
+// Frames resolves the stack of the error
+func (e *LoggedError) Frames() []Frame {
+	return stackFrames(e.Stack, maxCallers)
+}
+
+// Format implements fmt.Formatter, %+v adds the module, fields and stack on the following lines
+func (e *LoggedError) Format(f fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && f.Flag('+'):
+		_, _ = io.WriteString(f, e.Error())
+		_, _ = io.WriteString(f, "\nmodule="+e.Module+formatFields(e.Fields)+"\n")
+		_, _ = io.WriteString(f, e.StackTrace())
+	case verb == 'q':
+		_, _ = fmt.Fprintf(f, "%q", e.Error())
+	default:
+		_, _ = io.WriteString(f, e.Error())
+	}
+}
+
+// logError logs the message formatted with fmt.Errorf at level and returns it as a *LoggedError, it is shared
+// by Error, ErrorCtx, Panic and PanicCtx so the caller is always the same number of frames up. The message is
+// only formatted if the line is logged, or if there is no error argument to return instead, see errorArg
+func (l *Logger) logError(ctx context.Context, level Level, message string, args ...any) error {
+	logger := l.WithCallerSkip(1)
+	cause, found := errorArg(message, args)
+	if logged := level >= l.level.Get() && l.enabled(level); logged || !found {
+		formatted := fmt.Errorf(message, args...)
+		if logged {
+			logger.log(ctx, nil, nil, level, "%s", formatted.Error())
+		}
+		if !found {
+			cause = formatted
+		}
+	}
+	return logger.loggedError(ctx, cause)
+}
+
+// loggedError returns the LoggedError for a line logged with Logger.Error, the stack starts at the caller of its caller
+func (l *Logger) loggedError(ctx context.Context, cause error) error {
+	err := &LoggedError{
+		Err:    cause,
+		Module: l.name,
+		Fields: slices.Clone(l.contextFields(ctx)),
+	}
+	var logged *LoggedError
+	if errors.As(err.Err, &logged) {
+		err.Stack = logged.Stack
+	} else {
+		err.Stack = callers(2+l.callerSkip, maxCallers)
+	}
+	return err
+}
+
+// loggedAgain returns a logger with the fields of the LoggedError in the chain of err that this logger doesn't
+// have, and the error's stack, so an error logged by Logger.Error is logged again with its stack and fields.
+// if there is none it returns the logger as is and a nil stack
+func (l *Logger) loggedAgain(err error) (Logger, []uintptr) {
+	var logged *LoggedError
+	if !errors.As(err, &logged) {
+		return *l, nil
+	}
+
+	var missing []any
+	for _, field := range logged.Fields {
+		if !slices.ContainsFunc(l.fields, func(f Field) bool { return f.Key == field.Key }) {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) == 0 {
+		return *l, logged.Stack
+	}
+	return l.With(missing...), logged.Stack
+}
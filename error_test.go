@@ -0,0 +1,55 @@
+package logmanager
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoggedError(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	w := captureWriters(t)
+	logger := GetLogger("error")
+	withRequest := logger.With("request", 7)
+
+	err := withRequest.Error("reading config: %w", io.ErrUnexpectedEOF)
+	assert.Equal("reading config: unexpected EOF", err.Error())
+	assert.ErrorIs(err, io.ErrUnexpectedEOF, "%w wraps")
+
+	var logged *LoggedError
+	require.ErrorAs(err, &logged)
+	assert.Equal("error", logged.Module)
+	assert.Equal([]Field{{"request", 7}}, logged.Fields)
+	require.NotEmpty(logged.Stack)
+	assert.Contains(logged.StackTrace(), "@ github.com/axiomhq/logmanager.TestLoggedError")
+
+	assert.Equal(io.EOF, errors.Unwrap(logger.Error("read failed: %v", io.EOF)), "an error argument is wrapped as is")
+	joined := errors.Join(errors.New("first"), io.EOF)
+	assert.ErrorIs(logger.Error("%v", joined), io.EOF)
+
+	assert.Equal("reading config: unexpected EOF", fmt.Sprintf("%v", err))
+	assert.Equal(`"reading config: unexpected EOF"`, fmt.Sprintf("%q", err))
+	verbose := fmt.Sprintf("%+v", err)
+	assert.True(strings.HasPrefix(verbose, "reading config: unexpected EOF\nmodule=error request=7\nTrace (most recent call first, max 50 stack):\n"), verbose)
+
+	// logging it again reuses the stack and adds the fields
+	wrapped := fmt.Errorf("startup: %w", err)
+	assert.True(logger.IsError(wrapped))
+	var again *LoggedError
+	require.ErrorAs(logger.Error("giving up: %w", wrapped), &again)
+	assert.Equal(logged.Stack, again.Stack)
+
+	lines := w.Lines()
+	require.Len(lines, 5)
+	assert.Equal("reading config: unexpected EOF", lines[0].message, "%w is formatted like %v")
+	assert.Equal("Detected error: startup: reading config: unexpected EOF\n", lines[3].message)
+	assert.Equal(logged.Stack, lines[3].stack)
+	assert.Equal([]Field{{"request", 7}}, lines[3].fields)
+}
@@ -0,0 +1,73 @@
+package logmanager
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	exitFunc = os.Exit
+	exitLock sync.RWMutex
+
+	// fatalFlushTimeout is the nanoseconds Fatal and Panic wait for the writers to flush
+	fatalFlushTimeout = int64(5 * time.Second)
+)
+
+// SetExitFunc replaces os.Exit as the function Fatal exits with, e.g. so tests can check a fatal error
+// happened without exiting. Fatal returns if fn does. nil restores os.Exit
+func SetExitFunc(fn func(code int)) {
+	if fn == nil {
+		fn = os.Exit
+	}
+	exitLock.Lock()
+	defer exitLock.Unlock()
+	exitFunc = fn
+}
+
+// SetFatalFlushTimeout sets how long Fatal and Panic wait for the writers to flush before exiting or
+// panicking, 5 seconds by default
+func SetFatalFlushTimeout(timeout time.Duration) error {
+	if timeout <= 0 {
+		return errors.New("the flush timeout must be positive")
+	}
+	atomic.StoreInt64(&fatalFlushTimeout, int64(timeout))
+	return nil
+}
+
+// flushBeforeExit flushes every writer so the line that made the process give up isn't lost
+func flushBeforeExit() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(atomic.LoadInt64(&fatalFlushTimeout)))
+	defer cancel()
+	if err := Flush(ctx); err != nil {
+		println("Warning, could not flush the log writers:", err.Error())
+	}
+}
+
+// Fatal logs at the Critical level, flushes every writer and exits with status 1, see SetExitFunc
+// and SetFatalFlushTimeout. Deferred functions are not run
+func (l *Logger) Fatal(message string, args ...any) {
+	l.log(context.Background(), nil, nil, Critical, message, args...)
+	exit()
+}
+
+// Panic logs at the Critical level, flushes every writer and panics with the error Error would return,
+// a *LoggedError
+func (l *Logger) Panic(message string, args ...any) {
+	err := l.logError(context.Background(), Critical, message, args...)
+	flushBeforeExit()
+	panic(err)
+}
+
+// exit flushes every writer and exits with status 1
+func exit() {
+	flushBeforeExit()
+
+	exitLock.RLock()
+	fn := exitFunc
+	exitLock.RUnlock()
+	fn(1)
+}
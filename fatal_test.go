@@ -0,0 +1,86 @@
+package logmanager
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFatal(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	w := captureWriters(t)
+	logPath := path.Join(t.TempDir(), "fatal.log")
+	disk := NewDiskWriter(logPath, DiskWriterConfig{RotateDuration: time.Hour, MaximumLogFiles: 1})
+	defer disk.Close()
+	AddWriter(disk)
+
+	var written string
+	codes := []int{}
+	SetExitFunc(func(code int) {
+		codes = append(codes, code)
+		all, err := os.ReadFile(logPath)
+		require.NoError(err)
+		written = string(all)
+	})
+	defer SetExitFunc(nil)
+
+	logger := GetLogger("fatal")
+	logger.SetLogLevel(Critical)
+	logger.Fatal("giving up on %s", "db")
+	assert.Equal([]int{1}, codes)
+	assert.Contains(written, "crit fatal fatal_test.go:", "the writers are flushed before exiting")
+	assert.Contains(written, "giving up on db")
+
+	ctx := NewContext(context.Background(), logger.With("request", 3))
+	logger.FatalCtx(ctx, "giving up on request")
+	assert.Equal([]int{1, 1}, codes)
+
+	lines := w.Lines()
+	require.Len(lines, 2)
+	assert.Equal(Critical, lines[0].level)
+	assert.Equal([]Field{{"request", 3}}, lines[1].fields)
+
+	blocking := &blockingWriter{release: make(chan struct{})}
+	defer close(blocking.release)
+	ReplaceWriters(blocking)
+	require.NoError(SetFatalFlushTimeout(10 * time.Millisecond))
+	defer SetFatalFlushTimeout(5 * time.Second)
+	assert.Error(SetFatalFlushTimeout(0))
+
+	start := time.Now()
+	logger.Fatal("stuck")
+	assert.Equal([]int{1, 1, 1}, codes)
+	assert.Less(time.Since(start), time.Second, "a writer that doesn't flush doesn't keep the process from exiting")
+}
+
+func TestPanic(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	w := captureWriters(t)
+	logger := GetLogger("fatal")
+
+	var recovered any
+	func() {
+		defer func() { recovered = recover() }()
+		logger.Panic("corrupt index %d", 7)
+	}()
+
+	require.IsType(&LoggedError{}, recovered)
+	logged := recovered.(*LoggedError)
+	assert.EqualError(logged, "corrupt index 7")
+	assert.Equal("fatal", logged.Module)
+	assert.Equal("github.com/axiomhq/logmanager.TestPanic.func1", logged.Frames()[0].Function)
+
+	lines := w.Lines()
+	require.Len(lines, 1)
+	assert.Equal(Critical, lines[0].level)
+	assert.Equal("corrupt index 7", lines[0].message)
+}
@@ -0,0 +1,96 @@
+package logmanager
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// badKey is used as the key for values that were passed to With without a valid key
+const badKey = "!BADKEY"
+
+// Field is a key/value pair attached to a log line
+type Field struct {
+	Key   string
+	Value any
+}
+
+// F is shorthand for creating a Field
+func F(key string, value any) Field {
+	return Field{Key: key, Value: value}
+}
+
+// String will return the field formatted as key=value
+func (f Field) String() string {
+	return f.Key + "=" + quoteFieldValue(fieldValueString(f.Value))
+}
+
+// appendFields converts the alternating key/value pairs in keyvals into fields
+// Field values are taken as is, a key without a value or a value without a string key
+// is stored under badKey so nothing passed in gets lost
+func appendFields(fields []Field, keyvals ...any) []Field {
+	for len(keyvals) > 0 {
+		switch key := keyvals[0].(type) {
+		case Field:
+			fields = append(fields, key)
+			keyvals = keyvals[1:]
+		case string:
+			if len(keyvals) == 1 {
+				fields = append(fields, Field{Key: badKey, Value: key})
+				keyvals = keyvals[1:]
+				continue
+			}
+			fields = append(fields, Field{Key: key, Value: keyvals[1]})
+			keyvals = keyvals[2:]
+		default:
+			fields = append(fields, Field{Key: badKey, Value: key})
+			keyvals = keyvals[1:]
+		}
+	}
+	return fields
+}
+
+// fieldValueString returns the raw string representation of a field value
+func fieldValueString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "<nil>"
+	case string:
+		return v
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// quoteFieldValue will quote the value if it can't be read back unambiguously as part of key=value output
+func quoteFieldValue(s string) string {
+	if s == "" {
+		return `""`
+	}
+
+	for _, r := range s {
+		if r == '=' || r == '"' || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
+
+// formatFields formats fields as " key=value key2=value2", ready to be appended to a message
+func formatFields(fields []Field) string {
+	if len(fields) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	for _, field := range fields {
+		sb.WriteByte(' ')
+		sb.WriteString(field.String())
+	}
+	return sb.String()
+}
@@ -0,0 +1,53 @@
+package logmanager
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWith(t *testing.T) {
+	assert := assert.New(t)
+
+	logger := GetLogger("fields.test")
+	reqLogger := logger.With("request", 12, "user", "bob")
+	subLogger := reqLogger.With(F("shard", "eu-1"), "dangling")
+
+	assert.Empty(logger.Fields(), "With should not modify the original logger")
+	assert.Equal([]Field{{"request", 12}, {"user", "bob"}}, reqLogger.Fields())
+	assert.Equal([]Field{{"request", 12}, {"user", "bob"}, {"shard", "eu-1"}, {badKey, "dangling"}}, subLogger.Fields())
+	assert.Equal(logger.LogLevel(), subLogger.LogLevel())
+
+	subLogger.Info("look at the fields")
+}
+
+func TestFormatFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []Field
+		want   string
+	}{
+		{"none", nil, ""},
+		{"plain", []Field{{"a", 1}, {"b", "two"}}, " a=1 b=two"},
+		{"quoted", []Field{{"msg", "hello world"}, {"empty", ""}, {"eq", "a=b"}}, ` msg="hello world" empty="" eq="a=b"`},
+		{"error", []Field{{"err", errors.New("oh no")}}, ` err="oh no"`},
+		{"nil", []Field{{"v", nil}}, " v=<nil>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, formatFields(tt.fields))
+		})
+	}
+}
+
+func TestStructuredData(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("-", structuredData(nil))
+	assert.Equal(`[fields@32473 request="12" user="bob"]`, structuredData([]Field{{"request", 12}, {"user", "bob"}}))
+	assert.Equal(`[fields@32473 a_b="say \"hi\" \\ [x\]"]`, structuredData([]Field{{"a b", `say "hi" \ [x]`}}))
+	assert.Equal(`[fields@32473 _="x" aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa="y"]`,
+		structuredData([]Field{{"", "x"}, {"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", "y"}}))
+}
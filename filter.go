@@ -0,0 +1,133 @@
+package logmanager
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// FilterRules decide which lines a Filtered writer passes on
+type FilterRules struct {
+	// MinLevel drops everything below it
+	MinLevel Level
+
+	// Include and Exclude are module patterns in the same form as the logger spec, e.g. "db", "db.*" or "**.http",
+	// a pattern also applies to the modules below the ones it matches. With Include set only matching modules
+	// are passed on, and modules matching Exclude never are
+	Include []string
+	Exclude []string
+}
+
+// FilteredWriter is a Writer that only passes some lines on to another writer, see Filtered
+type FilteredWriter struct {
+	writer   RecordWriter
+	minLevel Level
+	include  []Rule
+	exclude  []Rule
+
+	modules sync.Map // module name -> bool, whether the module passes Include and Exclude
+}
+
+// Filtered wraps writer so it only gets the lines matching rules, e.g. to send only errors of the db modules to syslog:
+//
+//	syslog, err := logmanager.Filtered(syslogWriter, logmanager.FilterRules{MinLevel: logmanager.Error, Include: []string{"db"}})
+//
+// filters only apply on top of the module levels, lines below the level of their module never reach any writer.
+// an error is returned if a pattern is invalid
+func Filtered(writer Writer, rules FilterRules) (*FilteredWriter, error) {
+	w := &FilteredWriter{writer: AsRecordWriter(writer), minLevel: rules.MinLevel}
+
+	var err error
+	if w.include, err = compilePatterns(rules.Include); err != nil {
+		return nil, err
+	}
+	if w.exclude, err = compilePatterns(rules.Exclude); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func compilePatterns(patterns []string) ([]Rule, error) {
+	rules := make([]Rule, 0, len(patterns))
+	for _, pattern := range patterns {
+		rule := Rule{Pattern: strings.TrimSpace(pattern)}
+		if err := rule.compile(); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func matchAny(rules []Rule, segments []string) bool {
+	for _, rule := range rules {
+		if _, ok := rule.matchSpec(segments); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// allows reports whether lines of the level and module are passed on
+func (w *FilteredWriter) allows(level Level, module string) bool {
+	if level < w.minLevel {
+		return false
+	}
+
+	if ok, found := w.modules.Load(module); found {
+		return ok.(bool)
+	}
+
+	segments := strings.Split(module, ".")
+	ok := (len(w.include) == 0 || matchAny(w.include, segments)) && !matchAny(w.exclude, segments)
+	w.modules.Store(module, ok)
+	return ok
+}
+
+// Writer returns the wrapped writer
+func (w *FilteredWriter) Writer() Writer {
+	if legacy, ok := w.writer.(legacyWriter); ok {
+		return legacy.Writer
+	}
+	return w.writer
+}
+
+// BuildTheme ...
+func (w *FilteredWriter) BuildTheme(module string) ColorTheme {
+	return w.writer.BuildTheme(module)
+}
+
+// Enabled reports whether the rules allow the level and module, and the wrapped writer is enabled for them
+func (w *FilteredWriter) Enabled(level Level, module string) bool {
+	return w.allows(level, module) && writerEnabled(w.writer, level, module)
+}
+
+// Log ...
+func (w *FilteredWriter) Log(level Level, theme ColorTheme, module, filename string, line int, timestamp time.Time, message string) {
+	if w.allows(level, module) {
+		w.writer.Log(level, theme, module, filename, line, timestamp, message)
+	}
+}
+
+// WriteRecord ...
+func (w *FilteredWriter) WriteRecord(theme ColorTheme, r *Record) {
+	if w.allows(r.Level, r.Module) {
+		w.writer.WriteRecord(theme, r)
+	}
+}
+
+// Flush flushes the wrapped writer if it's a Flusher
+func (w *FilteredWriter) Flush() error {
+	if f, ok := w.Writer().(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Close closes the wrapped writer if it's a Closer
+func (w *FilteredWriter) Close() error {
+	if c, ok := w.Writer().(Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
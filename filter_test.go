@@ -0,0 +1,63 @@
+package logmanager
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFiltered(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	w := &captureWriter{}
+	filtered, err := Filtered(w, FilterRules{
+		MinLevel: Info,
+		Include:  []string{"db", "**.http"},
+		Exclude:  []string{"db.noisy"},
+	})
+	require.NoError(err)
+	assert.Equal(Writer(w), filtered.Writer())
+
+	for _, module := range []string{"db", "db.pool", "db.noisy", "db.noisy.child", "dbx", "api.http", "api"} {
+		filtered.WriteRecord(ColorTheme{}, &Record{Level: Info, Module: module})
+	}
+	filtered.WriteRecord(ColorTheme{}, &Record{Level: Debug, Module: "db"})
+	filtered.Log(Error, ColorTheme{}, "db.pool", "filter_test.go", 1, time.Now(), "legacy")
+
+	var modules []string
+	for _, line := range w.Lines() {
+		modules = append(modules, line.module)
+	}
+	assert.Equal([]string{"db", "db.pool", "api.http", "db.pool"}, modules)
+
+	_, err = Filtered(w, FilterRules{Exclude: []string{"db..x"}})
+	assert.Error(err)
+	_, err = Filtered(w, FilterRules{Include: []string{"[db"}})
+	assert.Error(err)
+}
+
+func TestWriterMinLevel(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	assert.Equal(Trace, NewConsoleWriter().MinLevel())
+
+	logPath := path.Join(t.TempDir(), "minlevel.log")
+	disk := NewDiskWriter(logPath, DiskWriterConfig{RotateDuration: time.Hour, MaximumLogFiles: 1})
+	assert.Equal(Info, disk.MinLevel())
+
+	disk.Log(Debug, ColorTheme{}, "minlevel", "filter_test.go", 1, time.Now(), "dropped by default")
+	disk.SetMinLevel(Debug)
+	disk.Log(Debug, ColorTheme{}, "minlevel", "filter_test.go", 2, time.Now(), "written after lowering")
+	require.NoError(disk.Close())
+
+	all, err := os.ReadFile(logPath)
+	require.NoError(err)
+	assert.NotContains(string(all), "dropped by default")
+	assert.Contains(string(all), "written after lowering")
+}
@@ -0,0 +1,69 @@
+package logmanager
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// LazyValue is a log argument or field value that is only computed when a line using it is written, see Lazy
+type LazyValue struct {
+	fn    func() any
+	once  sync.Once
+	value any
+}
+
+// Lazy wraps an expensive value so it's only computed if the line it's logged with passes the level of
+// the module and at least one writer is enabled for it, see Enabler. The function is called at most once,
+// however many writers use the value:
+//
+//	logger.Debug("state: %v", logmanager.Lazy(func() any { return server.DumpState() }))
+//
+// it can be used as a format argument with any verb, as a field value and with log/slog. Error, ErrorCtx, Panic
+// and PanicCtx also compute it for the error they return, unless one of the arguments is the error returned
+func Lazy(fn func() any) *LazyValue {
+	return &LazyValue{fn: fn}
+}
+
+// Value computes the value the first time it's called and returns it
+func (v *LazyValue) Value() any {
+	v.once.Do(func() {
+		v.value = v.fn()
+		v.fn = nil
+	})
+	return v.value
+}
+
+// Format implements fmt.Formatter by formatting the value with the same verb and flags
+func (v *LazyValue) Format(f fmt.State, verb rune) {
+	fmt.Fprintf(f, fmt.FormatString(f, verb), v.Value())
+}
+
+// String ...
+func (v *LazyValue) String() string {
+	return fieldValueString(v.Value())
+}
+
+// MarshalJSON marshals the value the way Logger.JSONify does
+func (v *LazyValue) MarshalJSON() ([]byte, error) {
+	return marshalRedacted(v.Value(), "")
+}
+
+// LogValue implements slog.LogValuer
+func (v *LazyValue) LogValue() slog.Value {
+	return slog.AnyValue(v.Value())
+}
+
+// LazyJSONify is JSONify only done if the line is written, e.g.
+//
+//	logger.Debug("config: %s", logger.LazyJSONify(cfg))
+//
+// marshals cfg only if debug is enabled
+func (l *Logger) LazyJSONify(v any) *LazyValue {
+	return Lazy(func() any { return l.JSONify(v) })
+}
+
+// LazyJSONifyIndent is JSONifyIndent only done if the line is written, see LazyJSONify
+func (l *Logger) LazyJSONifyIndent(v any) *LazyValue {
+	return Lazy(func() any { return l.JSONifyIndent(v) })
+}
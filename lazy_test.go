@@ -0,0 +1,100 @@
+package logmanager
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLazy(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	w := captureWriters(t)
+	logger := GetLogger("lazy")
+	logger.SetLogLevel(Info)
+
+	calls := 0
+	pi := func() any {
+		calls++
+		return 3.14159
+	}
+
+	logger.Debug("pi is %.2f", Lazy(pi))
+	assert.Equal(0, calls, "disabled lines don't evaluate their arguments")
+
+	cause := errors.New("broken")
+	logger.SetLogLevel(Critical)
+	err := logger.Error("pi is %.2f: %v", Lazy(pi), cause)
+	assert.Equal(0, calls, "the error argument is returned without formatting the message")
+	assert.ErrorIs(err, cause)
+	err = logger.Error("pi is %.2f", Lazy(pi))
+	assert.EqualError(err, "pi is 3.14", "the message is the error returned")
+	assert.Equal(1, calls)
+	logger.SetLogLevel(Info)
+	calls = 0
+
+	logger.Info("pi is %6.2f", Lazy(pi))
+	value := Lazy(pi)
+	withValue := logger.With("pi", value)
+	withValue.Info("twice")
+	withValue.Info("twice")
+	assert.Equal(2, calls, "every value is evaluated once")
+
+	lines := w.Lines()
+	require.Len(lines, 3)
+	assert.Equal("pi is   3.14", lines[0].message)
+	assert.Equal("pi=3.14159", lines[1].fields[0].String())
+
+	b, err := json.Marshal(map[string]any{"pi": value})
+	require.NoError(err)
+	assert.Equal(`{"pi":3.14159}`, string(b))
+	assert.Equal(slog.Float64Value(3.14159), value.LogValue())
+
+	cfg := redactCredentials{User: "app", Password: "hunter2"}
+	logger.Info("config %s", logger.LazyJSONify(cfg))
+	assert.Equal(`config {"user":"app","password":"[REDACTED]"}`, w.Lines()[3].message)
+	assert.Contains(fmt.Sprint(logger.LazyJSONifyIndent(cfg)), "\n  \"user\": \"app\"")
+}
+
+func TestWriterEnabled(t *testing.T) {
+	assert := assert.New(t)
+
+	console := &ConsoleWriter{out: io.Discard}
+	console.SetMinLevel(Error)
+	filtered, err := Filtered(&captureWriter{}, FilterRules{Exclude: []string{"lazy"}})
+	assert.NoError(err)
+
+	orig := Writers()
+	ReplaceWriters(console, filtered, Deduplicated(console, 0))
+	t.Cleanup(func() { ReplaceWriters(orig...) })
+
+	logger := GetLogger("lazy")
+	logger.SetLogLevel(Info)
+
+	evaluated := false
+	logger.Warn("%v", Lazy(func() any {
+		evaluated = true
+		return nil
+	}))
+	assert.False(evaluated, "no writer is enabled for warnings of the module")
+	assert.False(logger.enabled(Warning))
+	assert.True(logger.enabled(Error))
+	assert.False(slog.New(NewSlogHandler("lazy")).Enabled(t.Context(), slog.LevelWarn))
+
+	var buf bytes.Buffer
+	ReplaceWriters(NewSlogWriter(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelError})))
+	assert.False(logger.enabled(Warning))
+	logger.Error("%v", Lazy(func() any { return "evaluated" }))
+	assert.Contains(buf.String(), "msg=evaluated")
+
+	ReplaceWriters(&legacyCaptureWriter{})
+	assert.True(logger.enabled(Trace), "writers without Enabled get everything")
+}
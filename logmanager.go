@@ -1,23 +1,17 @@
 package logmanager
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
-	"os"
-	"path"
 	"runtime"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
-// Envvars
-var (
-	loggerSpec = os.Getenv("AXIOM_DEBUG")
-)
-
 // Level defines the log level, from Trace to Critical
 type Level uint64
 
@@ -47,6 +41,46 @@ func (l *Level) String() string {
 	}
 }
 
+// ParseLevel parses the name of a level, ignoring case. Both the full names ("warning")
+// and the short ones used by String ("warn") are accepted
+func ParseLevel(s string) (Level, error) {
+	switch {
+	case strings.EqualFold(s, "trace"):
+		return Trace, nil
+	case strings.EqualFold(s, "debug"):
+		return Debug, nil
+	case strings.EqualFold(s, "info"):
+		return Info, nil
+	case strings.EqualFold(s, "warning"), strings.EqualFold(s, "warn"):
+		return Warning, nil
+	case strings.EqualFold(s, "error"):
+		return Error, nil
+	case strings.EqualFold(s, "critical"), strings.EqualFold(s, "crit"):
+		return Critical, nil
+	default:
+		return Info, fmt.Errorf("unknown log level %q", s)
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (l Level) MarshalText() ([]byte, error) {
+	if l > Critical {
+		return nil, fmt.Errorf("unknown log level %d", uint64(l))
+	}
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, together with MarshalText this
+// allows a Level to be used with flag.TextVar
+func (l *Level) UnmarshalText(text []byte) error {
+	level, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	l.Set(level)
+	return nil
+}
+
 // Levels
 const (
 	Trace Level = iota
@@ -59,29 +93,66 @@ const (
 
 var (
 	globalWriters     = []Writer{NewConsoleWriter()}
-	customWritersSet  uint64
 	globalWritersLock sync.RWMutex
+	// writersGeneration is bumped on every change to globalWriters, loggers compare it
+	// against the generation they built their descriptors for to pick up changes
+	writersGeneration uint64 = 1
 )
 
-// SetCustomWriters ...
-func SetCustomWriters(writers ...Writer) {
-	if atomic.LoadUint64(&customWritersSet) > 0 {
-		panic(errors.New("custom writers has already been set"))
-	}
+// setWriters replaces the global writers, the slice must not be modified afterwards
+func setWriters(writers []Writer) {
+	globalWriters = writers
+	atomic.AddUint64(&writersGeneration, 1)
+}
 
+// AddWriter adds a writer to the writers every logger sends to, loggers that have already logged
+// pick it up with their next log line
+func AddWriter(writer Writer) {
 	globalWritersLock.Lock()
 	defer globalWritersLock.Unlock()
 	// copy, so we can "atomically" replace globalWriters
-	newWriters := append([]Writer{}, writers...)
-	globalWriters = newWriters
-	atomic.StoreUint64(&customWritersSet, 1)
+	setWriters(append(slices.Clip(globalWriters), writer))
 }
 
-// getWriters will return the current global writers if the given src is nil
-func getWriters() []Writer {
+// RemoveWriter removes a writer added before, it returns false if the writer wasn't in use.
+// the writer is not closed
+func RemoveWriter(writer Writer) bool {
+	globalWritersLock.Lock()
+	defer globalWritersLock.Unlock()
+
+	i := slices.Index(globalWriters, writer)
+	if i == -1 {
+		return false
+	}
+	setWriters(slices.Delete(slices.Clone(globalWriters), i, i+1))
+	return true
+}
+
+// ReplaceWriters replaces all writers with the given ones, the previous writers are not closed
+func ReplaceWriters(writers ...Writer) {
+	globalWritersLock.Lock()
+	defer globalWritersLock.Unlock()
+	setWriters(slices.Clone(writers))
+}
+
+// SetCustomWriters ...
+//
+// Deprecated: use ReplaceWriters, which this is an alias for
+func SetCustomWriters(writers ...Writer) {
+	ReplaceWriters(writers...)
+}
+
+// getWriters will return the current global writers together with their generation
+func getWriters() ([]Writer, uint64) {
 	globalWritersLock.RLock()
 	defer globalWritersLock.RUnlock()
-	return globalWriters
+	return globalWriters, atomic.LoadUint64(&writersGeneration)
+}
+
+// Writers returns the writers currently in use
+func Writers() []Writer {
+	writers, _ := getWriters()
+	return slices.Clone(writers)
 }
 
 // ColorTheme ...
@@ -96,69 +167,54 @@ type Writer interface {
 	BuildTheme(module string) ColorTheme
 }
 
-// Logger is a logmanager base logger
+// Logger is a logmanager base logger, every Logger for the same module name shares its
+// level and writers, they only differ in the fields attached with With
 type Logger struct {
-	name  string
-	level Level
+	*moduleState
+	fields []Field
+	// callerSkip is the number of extra frames skipped to find the call site, see WithCallerSkip
+	callerSkip int
+	// scope identifies the fields, loggers derived from each other with With share the scopes of their
+	// common fields, see contextFields
+	scope *fieldScope
+}
 
-	themeGenerated   uint32
-	writeDescriptors []writeDescriptor
-	writeDescLock    *sync.RWMutex
+// fieldScope is created by every With that adds fields, on top of the scope of the logger it was called on
+type fieldScope struct {
+	parent *fieldScope
+	n      int // the number of fields of loggers with this scope
 }
 
 type writeDescriptor struct {
-	writer Writer
+	writer RecordWriter
 	theme  ColorTheme
 }
 
-// GetLogger will get a logger for the specified name
+// GetLogger will get a logger for the specified name, the level is taken from the logger spec
+// and follows any later changes made with SetSpec
 func GetLogger(name string) Logger {
-	// go through the loggerSpec and look for "Foo=Trace" or whatever
-	// it will always prefer longer module names that match but also match
-	// smaller ones for example, for module name foo.bar.baz, foo.bar=Trace will
-	// match but foo.bar.baz=Trace will be preferred it's not smart enough to
-	// figure out that foo.bar.ba should be ignored in preference of foo.bar but
-	// that is a compromise that i'm willing to make for simplicity and
-	// flexability
-	level := Info
-	levelHint := ""
-	for _, moduleInfo := range strings.Split(loggerSpec, ":") {
-		moduleName, moduleLevel, found := strings.Cut(moduleInfo, "=")
-		if !found {
-			continue
-		}
-
-		if len(moduleName) <= len(levelHint) {
-			continue
-		}
+	return Logger{moduleState: getModule(name)}
+}
 
-		if strings.HasPrefix(moduleName, name) || moduleName == "<root>" {
-			switch {
-			case strings.EqualFold(moduleLevel, "trace"):
-				level = Trace
-			case strings.EqualFold(moduleLevel, "info"):
-				level = Info
-			case strings.EqualFold(moduleLevel, "debug"):
-				level = Debug
-			case strings.EqualFold(moduleLevel, "warning"):
-				level = Warning
-			case strings.EqualFold(moduleLevel, "error"):
-				level = Error
-			case strings.EqualFold(moduleLevel, "critical"):
-				level = Critical
-			default:
-				println("Warning: couldn't understand moduleName/loggerLevel", moduleName, moduleLevel)
-				continue
-			}
-			levelHint = moduleName
-		}
+// With will return a new logger for the same module that attaches the given fields to every line it logs
+// keyvals are alternating keys and values, e.g. log.With("request", id, "user", name), Field values
+// can be passed in directly
+func (l *Logger) With(keyvals ...any) Logger {
+	derived := Logger{
+		moduleState: l.moduleState,
+		fields:      appendFields(slices.Clip(l.fields), keyvals...),
+		callerSkip:  l.callerSkip,
+		scope:       l.scope,
 	}
-
-	return Logger{
-		name:          name,
-		level:         level,
-		writeDescLock: &sync.RWMutex{},
+	if len(derived.fields) != len(l.fields) {
+		derived.scope = &fieldScope{parent: l.scope, n: len(derived.fields)}
 	}
+	return derived
+}
+
+// Fields returns the fields attached to this logger
+func (l *Logger) Fields() []Field {
+	return slices.Clone(l.fields)
 }
 
 // IsDebugEnabled will return if debug is enabled, for this specific logger.
@@ -172,7 +228,8 @@ func (l *Logger) LogLevel() Level {
 	return l.level.Get()
 }
 
-// SetLogLevel will set the given log level
+// SetLogLevel will set the given log level, this applies to every logger for the same module
+// until the spec is changed with SetSpec
 func (l *Logger) SetLogLevel(level Level) {
 	l.level.Set(level)
 }
@@ -180,80 +237,106 @@ func (l *Logger) SetLogLevel(level Level) {
 // Logger logging methods
 
 // Trace ...
-func (l *Logger) Trace(message string, args ...any) { l.Log(Trace, message, args...) }
+func (l *Logger) Trace(message string, args ...any) {
+	l.log(context.Background(), nil, nil, Trace, message, args...)
+}
 
 // Debug ...
-func (l *Logger) Debug(message string, args ...any) { l.Log(Debug, message, args...) }
+func (l *Logger) Debug(message string, args ...any) {
+	l.log(context.Background(), nil, nil, Debug, message, args...)
+}
 
 // Info ...
-func (l *Logger) Info(message string, args ...any) { l.Log(Info, message, args...) }
+func (l *Logger) Info(message string, args ...any) {
+	l.log(context.Background(), nil, nil, Info, message, args...)
+}
 
 // Warn ...
-func (l *Logger) Warn(message string, args ...any) { l.Log(Warning, message, args...) }
+func (l *Logger) Warn(message string, args ...any) {
+	l.log(context.Background(), nil, nil, Warning, message, args...)
+}
 
 // Critical ...
-func (l *Logger) Critical(message string, args ...any) { l.Log(Critical, message, args...) }
+func (l *Logger) Critical(message string, args ...any) {
+	l.log(context.Background(), nil, nil, Critical, message, args...)
+}
 
-// Error ...
+// Error logs at the Error level and returns the error as a *LoggedError, see errorArg.
+// the message is formatted with fmt.Errorf, so %w wraps its argument
 func (l *Logger) Error(message string, args ...any) error {
-	l.Log(Error, message, args...)
-	if len(args) > 0 {
+	return l.logError(context.Background(), Error, message, args...)
+}
+
+// errorArg returns the error passed as the first or last argument, that is the error Error returns instead of
+// the formatted message. a message with %w is always the one returned, so the error wraps its arguments as the message says
+func errorArg(message string, args []any) (error, bool) {
+	if len(args) > 0 && !strings.Contains(message, "%w") {
 		if err, ok := args[0].(error); ok {
-			return err
+			return err, true
 		} else if err, ok := args[len(args)-1].(error); ok {
-			return err
+			return err, true
 		}
 	}
-
-	return fmt.Errorf(message, args...)
+	return nil, false
 }
 
-func (l *Logger) buildDescriptors() {
-	writers := getWriters()
+// Log ...
+func (l *Logger) Log(level Level, message string, args ...any) {
+	l.log(context.Background(), nil, nil, level, message, args...)
+}
 
-	// should only be true on the first .Log
-	// init the theme for each writer for this package
-	l.writeDescLock.Lock()
-	for _, writer := range writers {
-		desc := writeDescriptor{writer: writer, theme: writer.BuildTheme(l.name)}
-		l.writeDescriptors = append(l.writeDescriptors, desc)
-	}
-	l.writeDescLock.Unlock()
+// loggedStack is the stack a line is logged with, and the depth it was captured with for the header
+// of Record.StackTrace
+type loggedStack struct {
+	pcs      []uintptr
+	maxDepth int
 }
 
-// Log ...
-func (l *Logger) Log(level Level, message string, args ...any) {
-	// safe if not called before writers are added
-	if atomic.CompareAndSwapUint32(&l.themeGenerated, 0, 1) {
-		l.buildDescriptors()
+// log is shared by every logging method so the caller is always the same number of frames up, plus callerSkip,
+// stack is only set for lines that come with a stack trace and err for lines about an error
+func (l *Logger) log(ctx context.Context, stack *loggedStack, err error, level Level, message string, args ...any) {
+	if level < l.level.Get() || !l.enabled(level) {
+		return
 	}
 
-	if level < l.level {
+	r := getRecord()
+	defer putRecord(r)
+	r.Level, r.Time, r.Module = level, time.Now().UTC(), l.name
+
+	r.setCaller(l.callerPC(2))
+	if !l.allowed(r) {
 		return
 	}
 
-	ts := time.Now().UTC()
-	_, filepath, line, ok := runtime.Caller(2)
-	if ok {
-		filepath = path.Base(filepath)
-	} else {
-		filepath = "__unknown__"
-		line = -1
+	r.Message = sprintf(message, args...)
+	r.Fields = l.contextFields(ctx)
+	if stack != nil {
+		r.Stack, r.stackDepth = stack.pcs, stack.maxDepth
 	}
+	if err != nil {
+		r.Causes = errorCauses(err)
+	}
+	l.output(r)
+}
 
-	msg := fmt.Sprintf(message, args...)
-	l.writeDescLock.RLock()
-	for _, desc := range l.writeDescriptors {
-		desc.writer.Log(level, desc.theme, l.name, filepath, line, ts, msg)
+// sprintf is fmt.Sprintf, but a message without arguments or verbs is used as is and %w formats like
+// it does for fmt.Errorf
+func sprintf(format string, args ...any) string {
+	if len(args) == 0 && strings.IndexByte(format, '%') == -1 {
+		return format
 	}
-	l.writeDescLock.RUnlock()
+	if strings.Contains(format, "%w") {
+		return fmt.Errorf(format, args...).Error()
+	}
+	return fmt.Sprintf(format, args...)
 }
 
 // JSONify will attempt to jsonify the given structure
 // useful for debugging
-// shorthand for encoding/json marshalling but handling errors and conversion to string
+// shorthand for encoding/json marshalling but handling errors and conversion to string.
+// secrets are redacted, including struct fields tagged `log:"redact"`, see Redactor
 func (l *Logger) JSONify(v any) string {
-	b, err := json.Marshal(v)
+	b, err := marshalRedacted(v, "")
 	if err != nil {
 		return fmt.Sprintf("ERRMARSHALLING=%s", err)
 	}
@@ -262,9 +345,9 @@ func (l *Logger) JSONify(v any) string {
 
 // JSONifyIndent will attempt to jsonify the given structure - with opinionated indenting
 // useful for debugging
-// shorthand for encoding/json marshalling but handling errors and conversion to string
+// shorthand for encoding/json marshalling but handling errors and conversion to string, secrets are redacted like JSONify
 func (l *Logger) JSONifyIndent(v any) string {
-	b, err := json.MarshalIndent(v, "", "  ")
+	b, err := marshalRedacted(v, "  ")
 	if err != nil {
 		return fmt.Sprintf("ERRMARSHALLING=%s", err)
 	}
@@ -280,9 +363,13 @@ func (l *Logger) IsError(err error) bool {
 		return false
 	}
 
-	msg := SPrintStack(3, 8)
-	msg += fmt.Sprintf("Detected error: %v\n", err)
-	l.Log(Error, "%s", msg)
+	// an error returned by Error is logged with the stack and fields it was logged with the first time
+	logger, pcs := l.loggedAgain(err)
+	stack := &loggedStack{pcs: pcs, maxDepth: maxCallers}
+	if pcs == nil {
+		stack = &loggedStack{pcs: callers(1+l.callerSkip, 8), maxDepth: 8}
+	}
+	logger.log(context.Background(), stack, err, Error, "Detected error: %v\n", err)
 
 	return true
 }
@@ -326,9 +413,9 @@ func (l *Logger) Recover(v any) error {
 		err = fmt.Errorf("unknown panic error: %v", v)
 	}
 
-	msg := SPrintStack(5, maxCallers)
-	msg += fmt.Sprintf("Detected panic: %v\n", err)
-	l.Log(Error, "%s", msg)
+	// skip the deferred function and the panic
+	stack := &loggedStack{pcs: callers(3+l.callerSkip, maxCallers), maxDepth: maxCallers}
+	l.log(context.Background(), stack, err, Error, "Detected panic: %v\n", err)
 
 	return err
 }
@@ -337,17 +424,20 @@ func (l *Logger) Recover(v any) error {
 // if one is returned. This function is intended to be used in
 // defer scenarios, where the error would otherwise be lost
 func (l *Logger) CheckErr(f func() error) {
-	l.IsError(f())
+	// attribute the error to the code deferring CheckErr, not CheckErr
+	logger := l.WithCallerSkip(1)
+	logger.IsError(f())
 }
 
 // PrintStackTrace will print the current stack out to the info logger channel
 func (l *Logger) PrintStackTrace() {
-	l.Log(Info, "%s", SPrintStack(3, maxCallers))
+	stack := &loggedStack{pcs: callers(1+l.callerSkip, maxCallers), maxDepth: maxCallers}
+	l.log(context.Background(), stack, nil, Info, "Current stack")
 }
 
-// PrintCaller prints caller of this function
+// PrintCaller prints caller of this function, helpers are skipped like for the log lines, see Helper
 func (l *Logger) PrintCaller(skip int) {
-	l.Log(Info, "%s", SPrintCaller(skip+2))
+	l.log(context.Background(), nil, nil, Info, "%s", sprintCaller(l.callerPC(skip+1)))
 }
 
 // columnedLines takes care of formatting columned output
@@ -370,93 +460,72 @@ func (c *columnedLines) Add(columns ...string) {
 	}
 }
 
-func (c *columnedLines) ColumnString(columns []string) (line string) {
+func (c *columnedLines) ColumnString(columns []string) string {
+	var sb strings.Builder
+	c.writeColumns(&sb, columns)
+	return sb.String()
+}
+
+func (c *columnedLines) writeColumns(sb *strings.Builder, columns []string) {
 	for i, column := range columns {
-		size := c.columnSizes[i]
-		line += column
-		if padding := size - len(column); padding > 0 {
-			line += strings.Repeat(" ", padding)
+		sb.WriteString(column)
+		for padding := c.columnSizes[i] - len(column); padding > 0; padding-- {
+			sb.WriteByte(' ')
 		}
 	}
-	return
 }
 
-func (c *columnedLines) String() (lines string) {
+func (c *columnedLines) String() string {
+	var sb strings.Builder
 	for _, columns := range c.columnValues {
-		lines += c.ColumnString(columns) + "\n"
+		c.writeColumns(&sb, columns)
+		sb.WriteByte('\n')
 	}
-	return
+	return sb.String()
 }
 
-func (c *columnedLines) Reverse() (lines string) {
+func (c *columnedLines) Reverse() string {
+	var sb strings.Builder
 	for i := len(c.columnValues) - 1; i >= 0; i-- {
-		columns := c.columnValues[i]
-		lines += c.ColumnString(columns) + "\n"
+		c.writeColumns(&sb, c.columnValues[i])
+		sb.WriteByte('\n')
 	}
-	return
+	return sb.String()
 }
 
 const maxCallers = 50
-const cutDir = "watchly/src"
 
 // SPrintCaller returns a string with information about caller
 func SPrintCaller(skip int) string {
-	pc, file, line, ok := runtime.Caller(skip)
-	if !ok {
-		return "Cannot determine caller"
-	}
-	msg := "Caller:\n"
+	var pcs [1]uintptr
+	runtime.Callers(skip+1, pcs[:])
+	return sprintCaller(pcs[0])
+}
 
-	if srcDir := strings.Index(file, cutDir); srcDir != -1 {
-		file = "..." + file[srcDir+len(cutDir):]
+// sprintCaller formats the call site of a program counter returned by runtime.Callers for SPrintCaller
+func sprintCaller(pc uintptr) string {
+	if pc == 0 {
+		return "Cannot determine caller"
 	}
 
-	fn := runtime.FuncForPC(pc)
-	fnName := "unknown"
-	if fn != nil {
-		fnName = fn.Name()
+	c := lookupCaller(pc)
+	fnName := c.function
+	if fnName == "" {
+		fnName = "unknown"
 	}
 
-	msg += fmt.Sprintf("  %s:L%d  @ %s", file, line, fnName)
-	return msg
+	frame := Frame{Function: c.function, File: c.file, Line: c.line}
+	return fmt.Sprintf("Caller:\n  %s:L%d  @ %s", frame.Path(), c.line, fnName)
 }
 
 // SPrintStack will print the current stack to a returned string
 func SPrintStack(skip, maxDepth int) string {
 	maxDepth = min(maxDepth, maxCallers)
 
-	var msg string
-	msg = ""
-	msg += fmt.Sprintf("Trace (most recent call first, max %d stack):\n", maxDepth)
-
 	// we allocate a lot of callers because we don't know how many there are, so we just get a lot
 	callers := make([]uintptr, maxCallers)
 	totalCallers := runtime.Callers(skip, callers[:])
 	callers = callers[:totalCallers]
 
-	columns := columnedLines{}
-	frames := runtime.CallersFrames(callers)
-	foundFrames := 0
-	more := true
-	for more && foundFrames <= maxDepth {
-		var frame runtime.Frame
-		frame, more = frames.Next()
-
-		// skip some packages because they are uninteresting
-		if strings.HasPrefix(frame.Function, "runtime") ||
-			strings.HasPrefix(frame.Function, "testing") ||
-			strings.HasPrefix(frame.Function, "reflect") ||
-			strings.Contains(frame.Function, "github.com/stretchr/testify") {
-			continue
-		}
-
-		file := frame.File
-		if srcDir := strings.Index(file, cutDir); srcDir != -1 {
-			file = "..." + file[srcDir+len(cutDir):]
-		}
-
-		columns.Add(fmt.Sprintf("  %s:%d", file, frame.Line), fmt.Sprintf(" @ %s", frame.Function))
-		foundFrames++
-	}
-	return msg + columns.String()
+	return stackHeader(maxDepth) + formatStack(callers, maxDepth)
 }
@@ -2,8 +2,11 @@ package logmanager
 
 import (
 	"errors"
+	"path"
+	"slices"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -103,3 +106,83 @@ func TestLogAndExtend(_ *testing.T) {
 	SetCustomWriters(NewConsoleWriter())
 	logger.Info("Crashing... nah, got fixed")
 }
+
+func TestWriterChanges(t *testing.T) {
+	assert := assert.New(t)
+
+	first := captureWriters(t)
+	logger := GetLogger("writers.changes")
+	logger.Info("one")
+
+	second := &captureWriter{}
+	AddWriter(second)
+	logger.Info("two")
+	assert.Contains(Writers(), Writer(second))
+
+	assert.True(RemoveWriter(first))
+	assert.False(RemoveWriter(first))
+	logger.Info("three")
+
+	third := &captureWriter{}
+	ReplaceWriters(third)
+	SetCustomWriters(third)
+	logger.Info("four")
+
+	messages := func(w *captureWriter) (msgs []string) {
+		for _, line := range w.Lines() {
+			msgs = append(msgs, line.message)
+		}
+		return msgs
+	}
+	assert.Equal([]string{"one", "two"}, messages(first))
+	assert.Equal([]string{"two", "three"}, messages(second))
+	assert.Equal([]string{"four"}, messages(third))
+}
+
+type capturedLine struct {
+	level    Level
+	module   string
+	filename string
+	line     int
+	function string
+	message  string
+	fields   []Field
+	stack    []uintptr
+	causes   []ErrorCause
+	trace    string // the stack formatted by Record.StackTrace
+}
+
+// captureWriter keeps everything it's given so tests can inspect it
+type captureWriter struct {
+	mu    sync.Mutex
+	lines []capturedLine
+}
+
+func (w *captureWriter) BuildTheme(string) ColorTheme { return ColorTheme{} }
+
+func (w *captureWriter) Log(level Level, theme ColorTheme, module, filename string, line int, timestamp time.Time, message string) {
+	w.WriteRecord(theme, &Record{Level: level, Time: timestamp, Module: module, File: filename, Line: line, Message: message})
+}
+
+func (w *captureWriter) WriteRecord(_ ColorTheme, r *Record) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lines = append(w.lines, capturedLine{r.Level, r.Module, path.Base(r.File), r.Line, r.Function, r.Message, slices.Clone(r.Fields), r.Stack, slices.Clone(r.Causes), r.StackTrace()})
+}
+
+func (w *captureWriter) Lines() []capturedLine {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return slices.Clone(w.lines)
+}
+
+// captureWriters replaces the global writers with a captureWriter for the duration of the test
+func captureWriters(t *testing.T) *captureWriter {
+	t.Helper()
+
+	w := &captureWriter{}
+	orig := Writers()
+	ReplaceWriters(w)
+	t.Cleanup(func() { ReplaceWriters(orig...) })
+	return w
+}
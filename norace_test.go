@@ -0,0 +1,5 @@
+//go:build !race
+
+package logmanager
+
+const raceEnabled = false
@@ -0,0 +1,71 @@
+package logmanager
+
+import (
+	"slices"
+	"sync"
+)
+
+// Processor is called for every line before it is sent to the writers, it can change the record
+// or drop the line entirely by returning false. Processors are called concurrently by every goroutine that logs
+type Processor func(r *Record) bool
+
+// processor wraps a Processor so it can be found again for removal, funcs can't be compared
+type processor struct {
+	fn Processor
+}
+
+var (
+	// processors is replaced, never modified, so it can be used without holding the lock
+	processors     []*processor
+	processorsLock sync.RWMutex
+)
+
+// AddProcessor adds p to the processors every line goes through, after the processors added before it.
+// only lines at or above the level of their module get here, a processor lowering the level doesn't make it
+// skip the writers' own filtering. For example, to add the version to every line:
+//
+//	logmanager.AddProcessor(func(r *logmanager.Record) bool {
+//		r.Fields = append(r.Fields, logmanager.F("version", version))
+//		return true
+//	})
+//
+// the record's Fields can be changed freely, they are a copy of the logger's. Records are reused, so like writers
+// processors must not keep them after returning. The returned function removes the processor
+func AddProcessor(p Processor) (remove func()) {
+	entry := &processor{fn: p}
+
+	processorsLock.Lock()
+	processors = append(slices.Clip(processors), entry)
+	processorsLock.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			processorsLock.Lock()
+			defer processorsLock.Unlock()
+			if i := slices.Index(processors, entry); i != -1 {
+				processors = slices.Delete(slices.Clone(processors), i, i+1)
+			}
+		})
+	}
+}
+
+// process runs the record through the processors in order, it returns false as soon as one drops it
+func process(r *Record) bool {
+	processorsLock.RLock()
+	ps := processors
+	processorsLock.RUnlock()
+
+	if len(ps) == 0 {
+		return true
+	}
+
+	// the fields may be shared with the logger
+	r.Fields = slices.Clone(r.Fields)
+	for _, p := range ps {
+		if !p.fn(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,50 @@
+package logmanager
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessors(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	w := captureWriters(t)
+	base := GetLogger("processor")
+	logger := base.With("request", 1)
+
+	var order []string
+	removeFirst := AddProcessor(func(r *Record) bool {
+		order = append(order, "first")
+		r.Fields = append(r.Fields, F("version", "1.2.3"))
+		r.Fields[0].Value = 2
+		return true
+	})
+	removeSecond := AddProcessor(func(r *Record) bool {
+		order = append(order, "second")
+		if strings.Contains(r.Message, "noisy") {
+			return false
+		}
+		r.Message = strings.ToUpper(r.Message)
+		r.Level = Critical
+		return true
+	})
+	defer removeSecond()
+
+	logger.Info("hello")
+	logger.Info("noisy")
+	removeFirst()
+	removeFirst()
+	logger.Info("bye")
+
+	lines := w.Lines()
+	require.Len(lines, 2)
+	assert.Equal("HELLO", lines[0].message)
+	assert.Equal(Critical, lines[0].level)
+	assert.Equal([]Field{{"request", 2}, {"version", "1.2.3"}}, lines[0].fields)
+	assert.Equal([]Field{{"request", 1}}, lines[1].fields, "changes to the fields must not leak into the logger")
+	assert.Equal([]string{"first", "second", "first", "second", "second"}, order)
+}
@@ -0,0 +1,249 @@
+package logmanager
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RateLimit limits how many lines are logged with a token bucket: Rate lines per second on average,
+// with bursts of up to Burst lines. A zero Rate doesn't limit anything, a zero Burst allows bursts of one
+// second worth of lines
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// String returns the limit as used in the spec, e.g. "10/s"
+func (l RateLimit) String() string {
+	return strconv.FormatFloat(l.Rate, 'g', -1, 64) + "/s"
+}
+
+func (l RateLimit) enabled() bool {
+	return l.Rate > 0
+}
+
+func (l RateLimit) burst() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return max(1, math.Ceil(l.Rate))
+}
+
+// parseRate parses a rate like "10/s", "600/m" or "5/h", a bare number is per second
+func parseRate(s string) (float64, error) {
+	value, unit, _ := strings.Cut(s, "/")
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil || rate <= 0 || math.IsInf(rate, 0) {
+		return 0, fmt.Errorf("invalid rate %q, expected a positive number like 10/s", s)
+	}
+
+	switch unit {
+	case "", "s":
+		return rate, nil
+	case "m":
+		return rate / 60, nil
+	case "h":
+		return rate / 3600, nil
+	default:
+		return 0, fmt.Errorf("invalid rate %q, the unit must be s, m or h", s)
+	}
+}
+
+// parseRuleOption sets a "key=value" option of a spec entry on the rule:
+//
+//	rate=10/s     limit every call site of the modules to 10 lines per second
+//	burst=20      allow bursts of 20 lines per call site
+//	modrate=100/s limit each module as a whole
+//	modburst=200  allow bursts of 200 lines per module
+func (r *Rule) parseRuleOption(option string) error {
+	key, value, found := strings.Cut(option, "=")
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+	if !found || value == "" {
+		return fmt.Errorf("invalid option %q, expected key=value", option)
+	}
+
+	var err error
+	switch key {
+	case "rate":
+		r.CallsiteLimit.Rate, err = parseRate(value)
+	case "modrate":
+		r.ModuleLimit.Rate, err = parseRate(value)
+	case "burst":
+		r.CallsiteLimit.Burst, err = parseBurst(value)
+	case "modburst":
+		r.ModuleLimit.Burst, err = parseBurst(value)
+	default:
+		err = fmt.Errorf("unknown option %q", key)
+	}
+	return err
+}
+
+func parseBurst(s string) (int, error) {
+	burst, err := strconv.Atoi(s)
+	if err != nil || burst < 1 {
+		return 0, fmt.Errorf("invalid burst %q, expected a positive number", s)
+	}
+	return burst, nil
+}
+
+// rateLimits are the limits of a module together with their buckets
+type rateLimits struct {
+	callsite  RateLimit
+	callsites sync.Map // program counter -> *tokenBucket
+
+	module       RateLimit
+	moduleBucket *tokenBucket
+}
+
+// newRateLimits returns nil if neither limit is enabled, so the check is skipped
+func newRateLimits(m *moduleState, callsite, module RateLimit) *rateLimits {
+	if !callsite.enabled() && !module.enabled() {
+		return nil
+	}
+
+	limits := &rateLimits{callsite: callsite, module: module}
+	if module.enabled() {
+		limits.moduleBucket = &tokenBucket{limit: module, module: m}
+	}
+	return limits
+}
+
+// tokenBucket limits a call site, or a whole module if file is empty, and counts the lines it suppressed
+type tokenBucket struct {
+	limit  RateLimit
+	module *moduleState
+	file   string
+	line   int
+
+	mu         sync.Mutex
+	tokens     float64
+	last       time.Time
+	suppressed int
+	maxLevel   Level // the highest level suppressed
+}
+
+// take reports whether a line may be logged, counting it as suppressed if not
+func (b *tokenBucket) take(now time.Time, level Level) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.last.IsZero() {
+		b.tokens = b.limit.burst()
+	} else {
+		elapsed := max(0, now.Sub(b.last).Seconds())
+		b.tokens = min(b.limit.burst(), b.tokens+elapsed*b.limit.Rate)
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true
+	}
+
+	if b.suppressed == 0 {
+		registerSuppressed(b)
+		b.maxLevel = level
+	}
+	b.suppressed++
+	b.maxLevel = max(b.maxLevel, level)
+	return false
+}
+
+// allowed applies the rate limits of the module to the record, which must have its level, time and caller set
+func (m *moduleState) allowed(r *Record) bool {
+	limits := m.limits.Load()
+	if limits == nil {
+		return true
+	}
+
+	if limits.callsite.enabled() {
+		bucket, ok := limits.callsites.Load(r.PC)
+		if !ok {
+			bucket, _ = limits.callsites.LoadOrStore(r.PC, &tokenBucket{limit: limits.callsite, module: m, file: r.File, line: r.Line})
+		}
+		if !bucket.(*tokenBucket).take(r.Time, r.Level) {
+			return false
+		}
+	}
+
+	if limits.moduleBucket != nil && !limits.moduleBucket.take(r.Time, r.Level) {
+		return false
+	}
+	return true
+}
+
+// SetRateLimits limits how many lines every call site and the module as a whole can log, this applies
+// to every logger for the same module until the spec is changed. Zero limits turn limiting off.
+// suppressed lines are summarized periodically, see SetRateLimitSummaryInterval
+func (l *Logger) SetRateLimits(callsite, module RateLimit) {
+	l.limits.Store(newRateLimits(l.moduleState, callsite, module))
+}
+
+var (
+	// rateLimitSummaryInterval is the nanoseconds between summaries of suppressed lines
+	rateLimitSummaryInterval = int64(10 * time.Second)
+
+	suppressedLock    sync.Mutex
+	suppressedBuckets []*tokenBucket
+	summaryOnce       sync.Once
+)
+
+// SetRateLimitSummaryInterval sets how often lines suppressed by rate limits are summarized, 10 seconds by default
+func SetRateLimitSummaryInterval(interval time.Duration) error {
+	if interval <= 0 {
+		return errors.New("the summary interval must be positive")
+	}
+	atomic.StoreInt64(&rateLimitSummaryInterval, int64(interval))
+	return nil
+}
+
+// registerSuppressed adds a bucket to the next summary, the first time any line is suppressed
+// the goroutine writing the summaries is started
+func registerSuppressed(b *tokenBucket) {
+	suppressedLock.Lock()
+	suppressedBuckets = append(suppressedBuckets, b)
+	suppressedLock.Unlock()
+
+	summaryOnce.Do(func() {
+		go func() {
+			for {
+				interval := time.Duration(atomic.LoadInt64(&rateLimitSummaryInterval))
+				time.Sleep(interval)
+				summarizeSuppressed(interval)
+			}
+		}()
+	})
+}
+
+// summarizeSuppressed logs how many lines each bucket suppressed since the last summary, e.g.
+// "suppressed 4312 messages from foo.go:88 in last 10s", at the highest level suppressed
+func summarizeSuppressed(interval time.Duration) {
+	suppressedLock.Lock()
+	buckets := suppressedBuckets
+	suppressedBuckets = nil
+	suppressedLock.Unlock()
+
+	for _, b := range buckets {
+		b.mu.Lock()
+		suppressed, level := b.suppressed, b.maxLevel
+		b.suppressed = 0
+		b.mu.Unlock()
+
+		r := Record{Level: level, Time: time.Now().UTC(), Module: b.module.name}
+		if b.file == "" {
+			r.setCaller(0)
+			r.Message = fmt.Sprintf("suppressed %d messages from module %s in last %s", suppressed, b.module.name, interval)
+		} else {
+			r.File, r.Line = b.file, b.line
+			r.Message = fmt.Sprintf("suppressed %d messages from %s:%d in last %s", suppressed, path.Base(b.file), b.line, interval)
+		}
+		b.module.output(&r)
+	}
+}
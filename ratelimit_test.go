@@ -0,0 +1,68 @@
+package logmanager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRateLimits(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	spec, err := ParseSpec("foo=Debug,rate=10/s,burst=20:bar=Info, modrate=600/m:Warning,rate=5")
+	require.NoError(err)
+	require.Len(spec.Rules, 3)
+	assert.Equal(RateLimit{Rate: 10, Burst: 20}, spec.Rules[0].CallsiteLimit)
+	assert.Equal(RateLimit{Rate: 10}, spec.Rules[1].ModuleLimit)
+	assert.Equal(RateLimit{Rate: 5}, spec.Rules[2].CallsiteLimit)
+	assert.Equal("foo=debug,rate=10/s,burst=20:bar=info,modrate=10/s:<root>=warn,rate=5/s", spec.String())
+
+	for _, invalid := range []string{"foo=Debug,rate=fast", "foo=Debug,rate=0/s", "foo=Debug,rate=1/d", "foo=Debug,burst=-1", "foo=Debug,speed=1", "foo=Debug,rate"} {
+		_, err := ParseSpec(invalid)
+		assert.Error(err, invalid)
+	}
+}
+
+func TestRateLimits(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	w := captureWriters(t)
+	setTestSpec(t, "")
+	logger := GetLogger("ratelimit.callsite")
+	logger.SetRateLimits(RateLimit{Rate: 0.001, Burst: 3}, RateLimit{})
+
+	for i := range 10 {
+		logger.Info("first %d", i)
+	}
+	for i := range 10 {
+		logger.Warn("second %d", i)
+	}
+	require.Len(w.Lines(), 6, "every call site gets its own bucket")
+
+	summarizeSuppressed(10 * time.Second)
+	lines := w.Lines()
+	require.Len(lines, 8)
+	assert.Regexp(`^suppressed 7 messages from ratelimit_test.go:\d+ in last 10s$`, lines[6].message)
+	assert.Equal(Info, lines[6].level)
+	assert.Equal(Warning, lines[7].level, "summaries are logged at the highest level suppressed")
+
+	summarizeSuppressed(10 * time.Second)
+	assert.Len(w.Lines(), 8, "nothing to summarize without new suppressed lines")
+
+	setTestSpec(t, "ratelimit.module=info,modrate=1/h,modburst=2")
+	modLogger := GetLogger("ratelimit.module.child")
+	modLogger.Info("one")
+	modLogger.Info("two")
+	modLogger.Info("three")
+	logger.Info("not limited after the spec changed")
+	summarizeSuppressed(time.Minute)
+
+	lines = w.Lines()[8:]
+	require.Len(lines, 4)
+	assert.Equal("not limited after the spec changed", lines[2].message)
+	assert.Equal("suppressed 1 messages from module ratelimit.module.child in last 1m0s", lines[3].message)
+}
@@ -0,0 +1,152 @@
+package logmanager
+
+import (
+	"path"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Record is everything known about a single log line, it is what a RecordWriter gets
+type Record struct {
+	Level  Level
+	Time   time.Time
+	Module string
+
+	// the call site, PC is 0 and File "__unknown__" if it couldn't be determined
+	PC       uintptr
+	File     string // full path of the source file
+	Line     int
+	Function string // package path qualified function name, e.g. "github.com/foo/bar.(*Server).Serve"
+
+	Message string
+	Fields  []Field
+
+	// Stack holds the program counters of the stack for lines logged by IsError, Recover and PrintStackTrace,
+	// most recent call first, uninteresting frames (runtime, testing, ...) are left out
+	Stack []uintptr
+	// stackDepth is the depth Stack was captured with, maxCallers if it is 0
+	stackDepth int
+	// Causes is the error logged by IsError or Recover and every error it wraps, see CauseTree
+	Causes []ErrorCause
+}
+
+// setCaller fills in the call site from a program counter returned by runtime.Callers
+func (r *Record) setCaller(pc uintptr) {
+	if pc == 0 {
+		r.File, r.Line = "__unknown__", -1
+		return
+	}
+
+	c := lookupCaller(pc)
+	r.PC, r.File, r.Line, r.Function = pc, c.file, c.line, c.function
+}
+
+type caller struct {
+	file     string
+	line     int
+	function string
+}
+
+var (
+	// callerCache holds the resolved call site of every program counter seen, there is only
+	// a limited number of call sites in a program so it never needs to be cleaned up
+	callerCache     = map[uintptr]caller{}
+	callerCacheLock sync.RWMutex
+)
+
+// lookupCaller resolves a program counter to its call site, resolving is slow compared to everything else done per line
+func lookupCaller(pc uintptr) caller {
+	callerCacheLock.RLock()
+	c, ok := callerCache[pc]
+	callerCacheLock.RUnlock()
+	if ok {
+		return c
+	}
+
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	c = caller{file: frame.File, line: frame.Line, function: frame.Function}
+
+	callerCacheLock.Lock()
+	callerCache[pc] = c
+	callerCacheLock.Unlock()
+	return c
+}
+
+// recordPool reuses records, they are never kept by writers and processors
+var recordPool = sync.Pool{New: func() any { return new(Record) }}
+
+func getRecord() *Record {
+	return recordPool.Get().(*Record)
+}
+
+func putRecord(r *Record) {
+	*r = Record{}
+	recordPool.Put(r)
+}
+
+// StackTrace formats the stack of the record, it's empty if the record has no stack
+func (r *Record) StackTrace() string {
+	if len(r.Stack) == 0 {
+		return ""
+	}
+	maxDepth := r.stackDepth
+	if maxDepth == 0 {
+		maxDepth = maxCallers
+	}
+	return stackHeader(maxDepth) + formatStack(r.Stack, maxDepth)
+}
+
+// Frames resolves the stack of the record, it's empty if the record has no stack
+func (r *Record) Frames() []Frame {
+	return stackFrames(r.Stack, maxCallers)
+}
+
+// text is the whole line as writers without Record support get it: stack, message, causes and fields
+func (r *Record) text() string {
+	return r.StackTrace() + r.Message + r.CauseTree() + formatFields(r.Fields)
+}
+
+// RecordWriter is a Writer that gets the full Record of each line instead of the Log parameters,
+// every Writer added with AddWriter that implements it is sent records with WriteRecord and Log is never called.
+// the record must not be modified or kept after WriteRecord returns
+type RecordWriter interface {
+	Writer
+	WriteRecord(theme ColorTheme, r *Record)
+}
+
+// AsRecordWriter returns w if it is a RecordWriter, otherwise w is wrapped so its Log method gets
+// the file name without the directory and the stack and fields are added to the message
+func AsRecordWriter(w Writer) RecordWriter {
+	if rw, ok := w.(RecordWriter); ok {
+		return rw
+	}
+	return legacyWriter{w}
+}
+
+// Enabler is implemented by writers that can tell in advance whether they would write lines of a level
+// from a module. lines no writer is enabled for are dropped before their message is formatted, so
+// Lazy arguments aren't evaluated. Writers that don't implement it are always enabled
+type Enabler interface {
+	Enabled(level Level, module string) bool
+}
+
+// writerEnabled reports whether w is enabled for the level and module, see Enabler
+func writerEnabled(w Writer, level Level, module string) bool {
+	if legacy, ok := w.(legacyWriter); ok {
+		w = legacy.Writer
+	}
+	if e, ok := w.(Enabler); ok {
+		return e.Enabled(level, module)
+	}
+	return true
+}
+
+type legacyWriter struct {
+	Writer
+}
+
+// WriteRecord ...
+func (w legacyWriter) WriteRecord(theme ColorTheme, r *Record) {
+	w.Log(r.Level, theme, r.Module, path.Base(r.File), r.Line, r.Time, r.text())
+}
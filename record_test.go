@@ -0,0 +1,70 @@
+package logmanager
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// legacyCaptureWriter only implements Writer
+type legacyCaptureWriter struct {
+	filenames []string
+	messages  []string
+}
+
+func (w *legacyCaptureWriter) BuildTheme(string) ColorTheme { return ColorTheme{} }
+
+func (w *legacyCaptureWriter) Log(_ Level, _ ColorTheme, _, filename string, _ int, _ time.Time, message string) {
+	w.filenames = append(w.filenames, filename)
+	w.messages = append(w.messages, message)
+}
+
+func TestRecordWriter(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	w := captureWriters(t)
+	legacy := &legacyCaptureWriter{}
+	AddWriter(legacy)
+
+	logger := GetLogger("record")
+	withRequest := logger.With("request", 7)
+	withRequest.Info("hello")
+	logger.IsError(errors.New("broken"))
+
+	lines := w.Lines()
+	require.Len(lines, 2)
+	assert.Equal("record_test.go", lines[0].filename)
+	assert.Equal("github.com/axiomhq/logmanager.TestRecordWriter", lines[0].function)
+	assert.Equal([]Field{{"request", 7}}, lines[0].fields)
+	assert.Empty(lines[0].stack)
+
+	assert.Equal("Detected error: broken\n", lines[1].message)
+	require.NotEmpty(lines[1].stack)
+	assert.True(strings.HasPrefix(lines[1].trace, "Trace (most recent call first, max 8 stack):\n"),
+		"the header has the depth IsError captures the stack with")
+	assert.Contains(lines[1].trace, "@ github.com/axiomhq/logmanager.TestRecordWriter")
+	assert.NotContains(lines[1].trace, "testing.tRunner", "uninteresting frames should be left out")
+
+	require.Len(legacy.messages, 2)
+	assert.Equal([]string{"record_test.go", "record_test.go"}, legacy.filenames, "legacy writers get the base name")
+	assert.Equal("hello request=7", legacy.messages[0])
+	assert.True(strings.HasPrefix(legacy.messages[1], "Trace (most recent call first, max 8 stack):\n"))
+	assert.True(strings.HasSuffix(legacy.messages[1], "Detected error: broken\n"))
+
+	assert.Equal(RecordWriter(w), AsRecordWriter(w), "record writers aren't wrapped")
+
+	logger.PrintStackTrace()
+	lines = w.Lines()
+	require.Len(lines, 3)
+	assert.Equal("Current stack", lines[2].message)
+	r := Record{Stack: lines[2].stack}
+	assert.Contains(r.StackTrace(), "@ github.com/axiomhq/logmanager.TestRecordWriter")
+	assert.True(strings.HasPrefix(lines[2].trace, stackHeader(maxCallers)))
+	assert.True(strings.HasPrefix(legacy.messages[2], stackHeader(maxCallers)), "the header is the same as the one of SPrintStack")
+	assert.True(strings.HasPrefix(SPrintStack(1, maxCallers), stackHeader(maxCallers)))
+}
@@ -0,0 +1,241 @@
+package logmanager
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// moduleState is shared by every Logger for the same module name
+type moduleState struct {
+	name  string
+	level Level
+	rule  string // the spec entry that set the level, empty if none matched
+
+	// limits are the rate limits of the module, nil if there are none
+	limits atomic.Pointer[rateLimits]
+
+	// levelChange identifies the last SetModuleLevel or SetSpec that changed the level,
+	// so an expiring change only restores levels nobody has touched since
+	levelChange uint64
+
+	// writeDescriptors are built for the writers of descGeneration, and rebuilt when the writers change.
+	// the slice is replaced, never modified, so it can be used without holding the lock
+	descGeneration   uint64
+	writeDescriptors []writeDescriptor
+	writeDescLock    sync.RWMutex
+}
+
+// levelOverride is a level set with SetModuleLevel, it also applies to matching modules created afterwards
+type levelOverride struct {
+	name     string
+	children bool
+	level    Level
+	change   uint64 // the levelChange of the modules it set
+}
+
+// matches reports whether the override applies to module name
+func (o levelOverride) matches(name string) bool {
+	return name == o.name || o.children && strings.HasPrefix(name, o.name+".")
+}
+
+var (
+	modules      = map[string]*moduleState{}
+	levelChanges uint64
+	// overrides are applied in the order they were set, so the last matching one wins. ApplySpec clears them
+	overrides []levelOverride
+)
+
+// getModule returns the state for the module name, creating it if this is the first time it is used
+func getModule(name string) *moduleState {
+	registryLock.RLock()
+	m, ok := modules[name]
+	registryLock.RUnlock()
+	if ok {
+		return m
+	}
+
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if m, ok := modules[name]; ok {
+		return m
+	}
+
+	m = &moduleState{name: name}
+	m.applyRule(currentSpec)
+	modules[name] = m
+	return m
+}
+
+// applyRule sets the level and rate limits of the module from the spec, the registry lock must be held
+func (m *moduleState) applyRule(spec Spec) {
+	rule, ok := specRule(spec, m.name)
+	if !ok {
+		m.limits.Store(nil)
+	} else {
+		m.limits.Store(newRateLimits(m, rule.CallsiteLimit, rule.ModuleLimit))
+	}
+	m.applyLevel(rule, ok)
+}
+
+// applyLevel sets the level from the spec rule of the module, if it has one, followed by the
+// level overrides matching it. the registry lock must be held
+func (m *moduleState) applyLevel(rule Rule, ok bool) {
+	if ok {
+		m.level.Set(rule.Level)
+		m.rule = rule.String()
+	} else {
+		m.level.Set(Info)
+		m.rule = ""
+	}
+
+	for _, o := range overrides {
+		if o.matches(m.name) {
+			m.level.Set(o.level)
+			m.levelChange = o.change
+		}
+	}
+}
+
+// descriptors returns the writers of this module with their themes, building them
+// if the writers have changed since the last call
+func (m *moduleState) descriptors() []writeDescriptor {
+	generation := atomic.LoadUint64(&writersGeneration)
+
+	m.writeDescLock.RLock()
+	descs, built := m.writeDescriptors, m.descGeneration
+	m.writeDescLock.RUnlock()
+	if built == generation {
+		return descs
+	}
+
+	m.writeDescLock.Lock()
+	defer m.writeDescLock.Unlock()
+
+	writers, generation := getWriters()
+	if m.descGeneration == generation {
+		return m.writeDescriptors
+	}
+
+	// init the theme for each writer for this module
+	descs = make([]writeDescriptor, 0, len(writers))
+	for _, writer := range writers {
+		descs = append(descs, writeDescriptor{writer: AsRecordWriter(writer), theme: writer.BuildTheme(m.name)})
+	}
+	m.writeDescriptors, m.descGeneration = descs, generation
+	return descs
+}
+
+// enabled reports whether any writer is enabled for lines of the level, see Enabler
+func (m *moduleState) enabled(level Level) bool {
+	for _, desc := range m.descriptors() {
+		if writerEnabled(desc.writer, level, m.name) {
+			return true
+		}
+	}
+	return false
+}
+
+// output sends a record through the processors to every writer, level filtering is up to the caller
+func (m *moduleState) output(r *Record) {
+	if !process(r) {
+		return
+	}
+	if redactor := GetRedactor(); redactor != nil {
+		redactor.redactRecord(r)
+	}
+
+	for _, desc := range m.descriptors() {
+		desc.writer.WriteRecord(desc.theme, r)
+	}
+}
+
+// LoggerInfo describes a module that has been used with GetLogger
+type LoggerInfo struct {
+	Name  string
+	Level Level
+	// Rule is the spec entry the level was taken from, empty if no entry matched and the default was used.
+	// Level can differ from the rule if it was changed with Logger.SetLogLevel or SetModuleLevel since
+	Rule string
+}
+
+// Loggers returns every module that has been used with GetLogger so far, sorted by name
+func Loggers() []LoggerInfo {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	infos := make([]LoggerInfo, 0, len(modules))
+	for _, m := range modules {
+		infos = append(infos, LoggerInfo{Name: m.name, Level: m.level.Get(), Rule: m.rule})
+	}
+	slices.SortFunc(infos, func(a, b LoggerInfo) int { return cmp.Compare(a.Name, b.Name) })
+	return infos
+}
+
+// SetModuleLevel sets the level of module name, and with includeChildren the level of every module
+// below it as well, e.g. "foo" also changes "foo.bar" and "foo.bar.baz" but not "foobar". The level also
+// applies to matching modules that are first used later on, until the spec is changed with SetSpec.
+// with a non-zero expiry the level is taken from the spec again once it has passed, unless the level of
+// a module has been changed again in the meantime. The names of the changed modules in use are returned
+func SetModuleLevel(name string, level Level, includeChildren bool, expiry time.Duration) []string {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	levelChanges++
+	o := levelOverride{name: name, children: includeChildren, level: level, change: levelChanges}
+	// the new level replaces an earlier one for the same modules
+	overrides = slices.DeleteFunc(overrides, func(e levelOverride) bool {
+		return e.name == o.name && e.children == o.children
+	})
+	overrides = append(overrides, o)
+
+	var changed []string
+	for _, m := range modules {
+		if !o.matches(m.name) {
+			continue
+		}
+
+		changed = append(changed, m.name)
+		m.level.Set(level)
+		m.levelChange = o.change
+	}
+
+	if expiry > 0 {
+		time.AfterFunc(expiry, func() { expireOverride(o.change) })
+	}
+
+	slices.Sort(changed)
+	return changed
+}
+
+// expireOverride removes the level override of a SetModuleLevel and sets the modules it still applies to
+// back to the level and rule they get from the spec and the remaining overrides
+func expireOverride(change uint64) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	i := slices.IndexFunc(overrides, func(o levelOverride) bool { return o.change == change })
+	if i == -1 {
+		// replaced, or cleared by ApplySpec
+		return
+	}
+	overrides = slices.Delete(overrides, i, i+1)
+
+	for _, m := range modules {
+		if m.levelChange == change {
+			rule, ok := specRule(currentSpec, m.name)
+			m.applyLevel(rule, ok)
+		}
+	}
+}
+
+// knownModule reports whether module name has been used with GetLogger
+func knownModule(name string) bool {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	_, ok := modules[name]
+	return ok
+}
@@ -0,0 +1,70 @@
+package logmanager
+
+import (
+	"context"
+	"errors"
+)
+
+// Flusher is implemented by writers that buffer messages, Flush blocks until everything logged
+// before it was called has been written
+type Flusher interface {
+	Flush() error
+}
+
+// Closer is implemented by writers holding on to files, connections or goroutines. Close flushes
+// like Flusher and releases them, anything logged to the writer afterwards is dropped
+type Closer interface {
+	Close() error
+}
+
+// Flush flushes every writer in use that implements Flusher, it returns once they are all done
+// or ctx is done, whichever comes first
+func Flush(ctx context.Context) error {
+	return eachWriter(ctx, func(w Writer) error {
+		if f, ok := w.(Flusher); ok {
+			return f.Flush()
+		}
+		return nil
+	})
+}
+
+// Shutdown flushes and closes every writer in use, so nothing logged before is lost when the process exits:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+//	defer cancel()
+//	logmanager.Shutdown(ctx)
+//
+// it returns once all writers are done or ctx is done, whichever comes first. The writers stay in use,
+// so call it last thing before exiting
+func Shutdown(ctx context.Context) error {
+	return eachWriter(ctx, func(w Writer) error {
+		var errs []error
+		if f, ok := w.(Flusher); ok {
+			errs = append(errs, f.Flush())
+		}
+		if c, ok := w.(Closer); ok {
+			errs = append(errs, c.Close())
+		}
+		return errors.Join(errs...)
+	})
+}
+
+// eachWriter calls fn for every writer in use concurrently and waits for them or ctx
+func eachWriter(ctx context.Context, fn func(Writer) error) error {
+	writers := Writers()
+	results := make(chan error, len(writers))
+	for _, writer := range writers {
+		go func() { results <- fn(writer) }()
+	}
+
+	var errs []error
+	for range writers {
+		select {
+		case err := <-results:
+			errs = append(errs, err)
+		case <-ctx.Done():
+			return errors.Join(append(errs, ctx.Err())...)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,85 @@
+package logmanager
+
+import (
+	"context"
+	"net"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingWriter never finishes flushing until released
+type blockingWriter struct {
+	captureWriter
+	release chan struct{}
+}
+
+func (w *blockingWriter) Flush() error {
+	<-w.release
+	return nil
+}
+
+func TestShutdown(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	captureWriters(t)
+	logPath := path.Join(t.TempDir(), "shutdown.log")
+	disk := NewDiskWriter(logPath, DiskWriterConfig{RotateDuration: time.Hour, MaximumLogFiles: 1})
+	AddWriter(disk)
+
+	logger := GetLogger("shutdown")
+	logger.Info("flushed")
+	require.NoError(Flush(context.Background()))
+	all, err := os.ReadFile(logPath)
+	require.NoError(err)
+	assert.Contains(string(all), "flushed")
+
+	logger.Info("last words")
+	require.NoError(Shutdown(context.Background()))
+	all, err = os.ReadFile(logPath)
+	require.NoError(err)
+	assert.Contains(string(all), "last words", "everything should be written once Shutdown returns")
+
+	logger.Info("too late")
+	assert.NoError(disk.Flush())
+	assert.NoError(disk.Close(), "closing twice is fine")
+	all, err = os.ReadFile(logPath)
+	require.NoError(err)
+	assert.NotContains(string(all), "too late")
+
+	blocking := &blockingWriter{release: make(chan struct{})}
+	defer close(blocking.release)
+	ReplaceWriters(blocking)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(Shutdown(ctx), context.DeadlineExceeded)
+}
+
+func TestSyslogWriterFlush(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(err)
+	defer conn.Close()
+
+	writer, err := NewSyslogWriter("udp", conn.LocalAddr().String())
+	require.NoError(err)
+	writer.Log(Error, ColorTheme{}, "syslog", "shutdown_test.go", 1, time.Now(), "sent before close")
+	require.NoError(writer.Flush())
+
+	buf := make([]byte, 1024)
+	require.NoError(conn.SetReadDeadline(time.Now().Add(time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(err)
+	assert.Contains(string(buf[:n]), "sent before close")
+
+	require.NoError(writer.Close())
+	writer.Log(Error, ColorTheme{}, "syslog", "shutdown_test.go", 2, time.Now(), "dropped")
+	assert.NoError(writer.Flush())
+}
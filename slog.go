@@ -0,0 +1,229 @@
+package logmanager
+
+import (
+	"context"
+	"log/slog"
+	"path"
+	"slices"
+	"time"
+)
+
+// slog has no equivalent of Trace and Critical, these are the levels they are mapped to.
+// slog handlers render them as "DEBUG-4" and "ERROR+4" unless told otherwise with ReplaceAttr
+const (
+	SlogLevelTrace    = slog.LevelDebug - 4
+	SlogLevelCritical = slog.LevelError + 4
+)
+
+// attribute keys used by SlogWriter
+const (
+	SlogModuleKey = "module"
+	SlogFileKey   = "file"
+	SlogLineKey   = "line"
+	SlogStackKey  = "stack"
+	SlogCausesKey = "causes"
+)
+
+// slogLevel maps a Level onto the closest slog.Level
+func slogLevel(level Level) slog.Level {
+	switch level {
+	case Trace:
+		return SlogLevelTrace
+	case Debug:
+		return slog.LevelDebug
+	case Info:
+		return slog.LevelInfo
+	case Warning:
+		return slog.LevelWarn
+	case Error:
+		return slog.LevelError
+	default:
+		return SlogLevelCritical
+	}
+}
+
+// levelFromSlog maps a slog.Level onto a Level, anything in between two slog levels
+// is rounded down so e.g. slog.LevelInfo+2 is still Info
+func levelFromSlog(level slog.Level) Level {
+	switch {
+	case level < slog.LevelDebug:
+		return Trace
+	case level < slog.LevelInfo:
+		return Debug
+	case level < slog.LevelWarn:
+		return Info
+	case level < slog.LevelError:
+		return Warning
+	case level < SlogLevelCritical:
+		return Error
+	default:
+		return Critical
+	}
+}
+
+// SlogHandler is a slog.Handler that sends records through the logmanager writers
+// the handler behaves like a Logger for the module it was created for, so the level
+// is taken from the spec just like GetLogger.
+// a group is a child module, so slog.New(NewSlogHandler("foo")).WithGroup("bar") logs as "foo.bar" with
+// the level of "foo.bar". groups are preserved by prefixing attribute keys with the group names as well,
+// e.g. "bar.status"
+type SlogHandler struct {
+	logger *Logger
+	prefix string
+	fields []Field
+}
+
+var _ slog.Handler = (*SlogHandler)(nil)
+
+// NewSlogHandler returns a slog.Handler for the given module
+//
+//	log := slog.New(logmanager.NewSlogHandler("foo.bar"))
+func NewSlogHandler(module string) *SlogHandler {
+	logger := GetLogger(module)
+	return &SlogHandler{logger: &logger}
+}
+
+// Enabled ...
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	l := levelFromSlog(level)
+	return l >= h.logger.LogLevel() && h.logger.enabled(l)
+}
+
+// Handle ...
+// fields of a logger stored in ctx with NewContext are added in front of the record's attributes
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	ts := r.Time
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+
+	record := getRecord()
+	defer putRecord(record)
+	record.Level, record.Time, record.Module, record.Message = levelFromSlog(r.Level), ts.UTC(), h.logger.name, r.Message
+	record.setCaller(r.PC)
+	if !h.logger.allowed(record) {
+		return nil
+	}
+
+	fields := append(slices.Clip(h.logger.contextFields(ctx)), h.fields...)
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendAttr(fields, h.prefix, a)
+		return true
+	})
+	record.Fields = fields
+	h.logger.output(record)
+	return nil
+}
+
+// WithAttrs ...
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	h2 := *h
+	h2.fields = slices.Clip(h.fields)
+	for _, a := range attrs {
+		h2.fields = appendAttr(h2.fields, h.prefix, a)
+	}
+	return &h2
+}
+
+// WithGroup ...
+// the returned handler logs as the child module name of the handler's module, see SlogHandler
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	module := name
+	if h.logger.name != "" {
+		module = h.logger.name + "." + name
+	}
+	logger := GetLogger(module)
+
+	h2 := *h
+	h2.logger = &logger
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// appendAttr converts a slog.Attr into fields, groups are flattened into prefixed keys
+// and empty attributes are dropped as slog.Handler requires
+func appendAttr(fields []Field, prefix string, a slog.Attr) []Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	if a.Value.Kind() != slog.KindGroup {
+		return append(fields, Field{Key: prefix + a.Key, Value: a.Value.Any()})
+	}
+
+	// groups with an empty key are inlined
+	if a.Key != "" {
+		prefix += a.Key + "."
+	}
+	for _, ga := range a.Value.Group() {
+		fields = appendAttr(fields, prefix, ga)
+	}
+	return fields
+}
+
+// SlogWriter is a Writer that passes everything on to a slog.Handler, which allows
+// any slog handler to be used as an output, e.g.
+//
+//	logmanager.AddWriter(logmanager.NewSlogWriter(slog.NewJSONHandler(os.Stdout, nil)))
+//
+// the module is added as the "module" attribute and the caller as a "source" group
+// containing "file" and "line", the same place slog puts it
+type SlogWriter struct {
+	handler slog.Handler
+}
+
+// NewSlogWriter returns a Writer sending to the given handler
+func NewSlogWriter(handler slog.Handler) *SlogWriter {
+	return &SlogWriter{handler: handler}
+}
+
+// BuildTheme ...
+func (w *SlogWriter) BuildTheme(string) ColorTheme { return ColorTheme{} }
+
+// Enabled ...
+func (w *SlogWriter) Enabled(level Level, _ string) bool {
+	return w.handler.Enabled(context.Background(), slogLevel(level))
+}
+
+// Log ...
+func (w *SlogWriter) Log(level Level, theme ColorTheme, module, filename string, line int, timestamp time.Time, message string) {
+	w.WriteRecord(theme, &Record{Level: level, Time: timestamp, Module: module, File: filename, Line: line, Message: message})
+}
+
+// WriteRecord ...
+// the stack of the record, if any, is added as the "stack" attribute holding a []Frame, and the causes as "causes"
+func (w *SlogWriter) WriteRecord(_ ColorTheme, r *Record) {
+	ctx := context.Background()
+	sl := slogLevel(r.Level)
+	if !w.handler.Enabled(ctx, sl) {
+		return
+	}
+
+	record := slog.NewRecord(r.Time, sl, r.Message, 0)
+	record.AddAttrs(
+		slog.String(SlogModuleKey, r.Module),
+		slog.Group(slog.SourceKey, slog.String(SlogFileKey, path.Base(r.File)), slog.Int(SlogLineKey, r.Line)),
+	)
+	for _, field := range r.Fields {
+		record.AddAttrs(slog.Any(field.Key, field.Value))
+	}
+	if len(r.Stack) > 0 {
+		record.AddAttrs(slog.Any(SlogStackKey, r.Frames()))
+	}
+	if len(r.Causes) > 0 {
+		record.AddAttrs(slog.Any(SlogCausesKey, r.Causes))
+	}
+
+	if err := w.handler.Handle(ctx, record); err != nil {
+		println("Warning, slog handler failed:", err.Error())
+	}
+}
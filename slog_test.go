@@ -0,0 +1,88 @@
+package logmanager
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlogLevels(t *testing.T) {
+	for _, level := range []Level{Trace, Debug, Info, Warning, Error, Critical} {
+		assert.Equal(t, level, levelFromSlog(slogLevel(level)))
+	}
+
+	assert.Equal(t, Info, levelFromSlog(slog.LevelInfo+2))
+	assert.Equal(t, Trace, levelFromSlog(slog.LevelDebug-1))
+	assert.Equal(t, Critical, levelFromSlog(slog.LevelError+10))
+}
+
+func TestSlogHandler(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	h := NewSlogHandler("slog.test")
+	w := captureWriters(t)
+	log := slog.New(h)
+
+	assert.False(log.Enabled(t.Context(), slog.LevelDebug), "default level should be info")
+	log.Debug("not logged")
+
+	log.With("request", 12).WithGroup("http").Info("handled", "status", 200, slog.Group("req", "method", "GET"), slog.Attr{})
+	log.Error("failed", slog.Group("", "inlined", true))
+
+	lines := w.Lines()
+	require.Len(lines, 2)
+
+	assert.Equal(Info, lines[0].level)
+	assert.Equal("slog.test.http", lines[0].module, "a group is a child module")
+	assert.Equal("slog_test.go", lines[0].filename)
+	assert.NotEqual(-1, lines[0].line)
+	assert.Equal("handled", lines[0].message)
+	assert.Equal([]Field{{"request", int64(12)}, {"http.status", int64(200)}, {"http.req.method", "GET"}}, lines[0].fields)
+
+	assert.Equal(Error, lines[1].level)
+	assert.Equal("slog.test", lines[1].module)
+	assert.Equal([]Field{{"inlined", true}}, lines[1].fields)
+
+	// the level of a group follows the spec for its module
+	setTestSpec(t, "slog.test.db=Debug")
+	db := log.WithGroup("db")
+	assert.True(db.Enabled(t.Context(), slog.LevelDebug))
+	assert.False(log.Enabled(t.Context(), slog.LevelDebug))
+	log.WithGroup("cache").Debug("not logged, slog.test.cache is a module of its own")
+	db.Debug("connected", "host", "localhost")
+
+	lines = w.Lines()
+	require.Len(lines, 3)
+	assert.Equal("slog.test.db", lines[2].module)
+	assert.Equal([]Field{{"db.host", "localhost"}}, lines[2].fields)
+}
+
+func TestSlogWriter(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var buf bytes.Buffer
+	w := NewSlogWriter(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	w.Log(Debug, ColorTheme{}, "slog.writer", "foo.go", 10, ts, "filtered out by the handler")
+	w.WriteRecord(ColorTheme{}, &Record{Level: Critical, Time: ts, Module: "slog.writer", File: "/src/foo.go", Line: 12, Message: "oh no", Fields: []Field{{"request", 12}}})
+
+	var got map[string]any
+	require.NoError(json.Unmarshal(buf.Bytes(), &got), "there should be exactly one JSON line")
+
+	assert.Equal(map[string]any{
+		"time":    "2024-01-02T03:04:05Z",
+		"level":   "ERROR+4",
+		"msg":     "oh no",
+		"module":  "slog.writer",
+		"source":  map[string]any{"file": "foo.go", "line": float64(12)},
+		"request": float64(12),
+	}, got)
+}
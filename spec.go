@@ -0,0 +1,467 @@
+package logmanager
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// rootModule is the name used in the spec for the level of every module not matched by anything else,
+// a bare level without a module name does the same
+const rootModule = "<root>"
+
+// Rule is a single "pattern=level" entry of a Spec, optionally followed by rate limits like
+// "pattern=level,rate=10/s,burst=20", see Rule.parseRuleOption
+type Rule struct {
+	Pattern string
+	Level   Level
+
+	// CallsiteLimit limits every call site of the modules the rule applies to, ModuleLimit each module as a whole
+	CallsiteLimit RateLimit
+	ModuleLimit   RateLimit
+
+	segments []string // nil for the root
+}
+
+// String ...
+func (r Rule) String() string {
+	s := r.Pattern + "=" + r.Level.String()
+	if r.CallsiteLimit.enabled() {
+		s += ",rate=" + r.CallsiteLimit.String()
+		if r.CallsiteLimit.Burst > 0 {
+			s += ",burst=" + strconv.Itoa(r.CallsiteLimit.Burst)
+		}
+	}
+	if r.ModuleLimit.enabled() {
+		s += ",modrate=" + r.ModuleLimit.String()
+		if r.ModuleLimit.Burst > 0 {
+			s += ",modburst=" + strconv.Itoa(r.ModuleLimit.Burst)
+		}
+	}
+	return s
+}
+
+// Spec is a parsed logger spec, e.g. "foo=Trace:foo.bar=Info:Warning".
+// a rule applies to the modules matching its pattern and everything below them, see specRule for
+// which rule wins if several match.
+// *Spec implements flag.Value, so it can be used for a command line flag that fails on typos:
+//
+//	var spec logmanager.Spec
+//	flag.Var(&spec, "log-spec", "module log levels")
+//	flag.Parse()
+//	logmanager.ApplySpec(spec)
+type Spec struct {
+	Rules []Rule
+}
+
+// String returns the spec in the form it is parsed from
+func (s Spec) String() string {
+	entries := make([]string, 0, len(s.Rules))
+	for _, rule := range s.Rules {
+		entries = append(entries, rule.String())
+	}
+	return strings.Join(entries, ":")
+}
+
+// Set replaces the spec with the parsed value, nothing is changed if any entry is invalid
+func (s *Spec) Set(value string) error {
+	spec, err := ParseSpec(value)
+	if err != nil {
+		return err
+	}
+	*s = spec
+	return nil
+}
+
+// SpecEntryError describes an entry of a spec that couldn't be parsed
+type SpecEntryError struct {
+	Index  int // index of the entry, counting from 0
+	Offset int // byte offset of the entry in the spec
+	Entry  string
+	Err    error
+}
+
+// Error ...
+func (e *SpecEntryError) Error() string {
+	return fmt.Sprintf("entry %d (offset %d) %q: %v", e.Index, e.Offset, e.Entry, e.Err)
+}
+
+// Unwrap ...
+func (e *SpecEntryError) Unwrap() error {
+	return e.Err
+}
+
+// SpecError is returned by ParseSpec when one or more entries couldn't be parsed
+type SpecError struct {
+	Entries []*SpecEntryError
+}
+
+// Error ...
+func (e *SpecError) Error() string {
+	msgs := make([]string, 0, len(e.Entries))
+	for _, entry := range e.Entries {
+		msgs = append(msgs, entry.Error())
+	}
+	return "invalid logger spec: " + strings.Join(msgs, ", ")
+}
+
+// Unwrap ...
+func (e *SpecError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Entries))
+	for _, entry := range e.Entries {
+		errs = append(errs, entry)
+	}
+	return errs
+}
+
+var (
+	// registryLock guards both the spec and the modules using it
+	registryLock sync.RWMutex
+	currentSpec  Spec
+)
+
+func init() {
+	spec, err := ParseSpec(specFromEnv())
+	if err != nil {
+		println("Warning:", err.Error())
+	}
+	currentSpec = spec
+}
+
+// ParseSpec parses a spec like "foo=Trace:foo.bar=Info,rate=10/s:Warning".
+// the rules for the valid entries are always returned, if any entries are invalid the error is a *SpecError
+func ParseSpec(spec string) (Spec, error) {
+	var (
+		parsed Spec
+		errs   []*SpecEntryError
+		offset int
+	)
+	for i, moduleInfo := range strings.Split(spec, ":") {
+		entryOffset := offset
+		offset += len(moduleInfo) + 1
+
+		moduleInfo = strings.TrimSpace(moduleInfo)
+		if moduleInfo == "" {
+			continue
+		}
+
+		rule, err := parseRule(moduleInfo)
+		if err != nil {
+			errs = append(errs, &SpecEntryError{Index: i, Offset: entryOffset, Entry: moduleInfo, Err: err})
+			continue
+		}
+		parsed.Rules = append(parsed.Rules, rule)
+	}
+
+	if len(errs) > 0 {
+		return parsed, &SpecError{Entries: errs}
+	}
+	return parsed, nil
+}
+
+func parseRule(moduleInfo string) (Rule, error) {
+	moduleInfo, options, hasOptions := strings.Cut(moduleInfo, ",")
+	moduleName, moduleLevel, found := strings.Cut(moduleInfo, "=")
+	if !found {
+		// a bare level sets the root
+		moduleName, moduleLevel = rootModule, moduleInfo
+	}
+
+	level, err := ParseLevel(strings.TrimSpace(moduleLevel))
+	if err != nil {
+		return Rule{}, err
+	}
+
+	rule := Rule{Pattern: strings.TrimSpace(moduleName), Level: level}
+	if hasOptions {
+		for _, option := range strings.Split(options, ",") {
+			if err := rule.parseRuleOption(option); err != nil {
+				return Rule{}, err
+			}
+		}
+	}
+	return rule, rule.compile()
+}
+
+// compile checks the pattern and splits it into segments
+func (r *Rule) compile() error {
+	if r.Pattern == rootModule {
+		r.segments = nil
+		return nil
+	}
+
+	segments := strings.Split(r.Pattern, ".")
+	for _, segment := range segments {
+		if segment == "" {
+			return fmt.Errorf("empty segment in module pattern %q", r.Pattern)
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("invalid module pattern %q: %w", r.Pattern, err)
+		}
+	}
+	r.segments = segments
+	return nil
+}
+
+// specMatch describes how well an entry matches a module name, see matchSpec
+type specMatch struct {
+	literals  int  // segments matched without any wildcards
+	exact     bool // the pattern matched the whole name, not just one of its parents
+	multiStar int  // "**" segments
+	globs     int  // other segments containing wildcards
+}
+
+// moreSpecific reports whether m is a more specific match than o
+func (m specMatch) moreSpecific(o specMatch) bool {
+	switch {
+	case m.literals != o.literals:
+		return m.literals > o.literals
+	case m.exact != o.exact:
+		return m.exact
+	case m.multiStar != o.multiStar:
+		return m.multiStar < o.multiStar
+	default:
+		return m.globs < o.globs
+	}
+}
+
+// matchSegments reports whether the pattern matches every segment of name, "**" matches any number
+// of segments (including none) and every other segment is matched with path.Match, so "*" matches exactly one
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := range len(name) + 1 {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// matchSpec reports if and how well the entry matches the module name. An entry applies to the modules
+// its pattern matches as well as everything below them, so foo.bar applies to foo.bar and foo.bar.baz but not foo.barbaz
+func (r Rule) matchSpec(name []string) (specMatch, bool) {
+	if r.segments == nil {
+		return specMatch{}, true
+	}
+
+	// try the whole name first, then its parents
+	for n := len(name); n > 0; n-- {
+		if !matchSegments(r.segments, name[:n]) {
+			continue
+		}
+
+		match := specMatch{exact: n == len(name)}
+		for _, segment := range r.segments {
+			switch {
+			case segment == "**":
+				match.multiStar++
+			case strings.ContainsAny(segment, `*?[\`):
+				match.globs++
+			default:
+				match.literals++
+			}
+		}
+		return match, true
+	}
+	return specMatch{}, false
+}
+
+// specRule finds the rule of the spec that applies to module name, modules without one log at Info.
+// the most specific entry wins: the one with the most literal segments, then one matching the whole
+// name over one matching a parent, then the one with the fewest wildcards. The root is only used if nothing
+// else matches, and if two entries are equally specific the later one wins
+func specRule(spec Spec, name string) (Rule, bool) {
+	segments := strings.Split(name, ".")
+
+	var (
+		rule           Rule
+		best           specMatch
+		found, matched bool
+	)
+	for _, r := range spec.Rules {
+		match, ok := r.matchSpec(segments)
+		if !ok {
+			continue
+		}
+
+		if r.segments == nil {
+			// the root only applies as long as nothing else matched
+			if !found {
+				rule, matched = r, true
+			}
+			continue
+		}
+
+		if found && best.moreSpecific(match) {
+			continue
+		}
+		found, matched, best = true, true, match
+		rule = r
+	}
+	return rule, matched
+}
+
+// SetSpec parses and applies the spec, see ApplySpec. Nothing is changed if the spec can't be parsed
+func SetSpec(spec string) error {
+	parsed, err := ParseSpec(spec)
+	if err != nil {
+		return err
+	}
+	return ApplySpec(parsed)
+}
+
+// ApplySpec replaces the logger spec (initially read from LOGMANAGER_SPEC or AXIOM_DEBUG) and updates the level of every module
+// used with GetLogger, including ones that already exist. Levels set with Logger.SetLogLevel or SetModuleLevel
+// are overwritten. an error is returned and nothing is changed if a rule has an invalid pattern
+func ApplySpec(spec Spec) error {
+	spec.Rules = slices.Clone(spec.Rules)
+	for i := range spec.Rules {
+		if err := spec.Rules[i].compile(); err != nil {
+			return err
+		}
+	}
+
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	currentSpec = spec
+	overrides = nil
+	levelChanges++
+	for _, m := range modules {
+		m.applyRule(spec)
+		m.levelChange = levelChanges
+	}
+	return nil
+}
+
+// CurrentSpec returns the logger spec currently in use
+func CurrentSpec() Spec {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	return Spec{Rules: slices.Clone(currentSpec.Rules)}
+}
+
+// readSpecFile reads a spec from a file, entries can be separated by newlines as well as ":"
+// and lines starting with "#" are ignored
+func readSpecFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	var entries []string
+	for line := range strings.Lines(string(b)) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	return strings.Join(entries, ":"), nil
+}
+
+// loadSpecFile applies the spec in the file at path, or the one from the environment if path is empty
+func loadSpecFile(path string) error {
+	if path == "" {
+		return SetSpec(specFromEnv())
+	}
+
+	spec, err := readSpecFile(path)
+	if err != nil {
+		return err
+	}
+	return SetSpec(spec)
+}
+
+// ReloadSpecOnSignal applies the spec from the file at path every time one of sigs is received, if no signals
+// are given SIGHUP is used. With an empty path the spec is read from LOGMANAGER_SPEC or AXIOM_DEBUG again.
+// the returned function stops listening for the signals
+func ReloadSpecOnSignal(path string, sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGHUP}
+	}
+
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, sigs...)
+	go func() {
+		for {
+			select {
+			case <-ch:
+				if err := loadSpecFile(path); err != nil {
+					println("Warning, could not reload logger spec:", err.Error())
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
+
+// WatchSpecFile applies the spec from the file at path and applies it again whenever the file changes.
+// changes are picked up by checking the modification time of the file every interval.
+// the returned function stops watching the file
+func WatchSpecFile(path string, interval time.Duration) (stop func(), err error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := loadSpecFile(path); err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		lastMod, lastSize := stat.ModTime(), stat.Size()
+		for {
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+
+			stat, err := os.Stat(path)
+			if err != nil || (stat.ModTime().Equal(lastMod) && stat.Size() == lastSize) {
+				continue
+			}
+			lastMod, lastSize = stat.ModTime(), stat.Size()
+
+			if err := loadSpecFile(path); err != nil {
+				println("Warning, could not reload logger spec:", err.Error())
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }, nil
+}
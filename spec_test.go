@@ -0,0 +1,232 @@
+package logmanager
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setTestSpec applies spec for the duration of the test
+func setTestSpec(t *testing.T, spec string) {
+	t.Helper()
+
+	orig := CurrentSpec()
+	require.NoError(t, SetSpec(spec))
+	t.Cleanup(func() { _ = ApplySpec(orig) })
+}
+
+func TestParseSpec(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	spec, err := ParseSpec("foo=Trace: foo.bar = info::<root>=Warning:Debug")
+	require.NoError(err)
+	assert.Equal([]Rule{
+		{Pattern: "foo", Level: Trace, segments: []string{"foo"}},
+		{Pattern: "foo.bar", Level: Info, segments: []string{"foo", "bar"}},
+		{Pattern: rootModule, Level: Warning},
+		{Pattern: rootModule, Level: Debug},
+	}, spec.Rules)
+	assert.Equal("foo=trace:foo.bar=info:<root>=warn:<root>=debug", spec.String())
+
+	spec, err = ParseSpec("foo=Trace:bar=Loud:Loud:foo..bar=Info:foo.[=Info")
+	assert.Equal([]Rule{{Pattern: "foo", Level: Trace, segments: []string{"foo"}}}, spec.Rules, "valid entries should still be returned")
+
+	var specErr *SpecError
+	require.ErrorAs(err, &specErr)
+	require.Len(specErr.Entries, 4)
+	assert.Equal(SpecEntryError{Index: 1, Offset: 10, Entry: "bar=Loud", Err: specErr.Entries[0].Err}, *specErr.Entries[0])
+	assert.ErrorContains(specErr.Entries[0], `unknown log level "Loud"`)
+	assert.Equal(2, specErr.Entries[1].Index)
+	assert.Equal(19, specErr.Entries[1].Offset)
+	assert.ErrorContains(specErr.Entries[2], "empty segment")
+	assert.ErrorIs(err, path.ErrBadPattern)
+}
+
+func TestSpecFlag(t *testing.T) {
+	assert := assert.New(t)
+
+	var spec Spec
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Var(&spec, "log-spec", "")
+
+	assert.Error(fs.Parse([]string{"--log-spec", "foo=Typo"}))
+	assert.Empty(spec.Rules)
+
+	assert.NoError(fs.Parse([]string{"--log-spec", "foo=Trace:Warning"}))
+	assert.Equal("foo=trace:<root>=warn", spec.String())
+
+	var level Level
+	fs.TextVar(&level, "log-level", Info, "")
+	assert.NoError(fs.Parse([]string{"--log-level", "debug"}))
+	assert.Equal(Debug, level)
+	assert.Error(fs.Parse([]string{"--log-level", "loud"}))
+}
+
+func TestLevelText(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, level := range []Level{Trace, Debug, Info, Warning, Error, Critical} {
+		text, err := level.MarshalText()
+		assert.NoError(err)
+
+		var parsed Level
+		assert.NoError(parsed.UnmarshalText(text))
+		assert.Equal(level, parsed)
+	}
+
+	_, err := Level(42).MarshalText()
+	assert.Error(err)
+
+	level, err := ParseLevel("WARNING")
+	assert.NoError(err)
+	assert.Equal(Warning, level)
+}
+
+func TestSpecLevel(t *testing.T) {
+	tests := []struct {
+		spec   string
+		module string
+		level  Level
+		rule   string
+	}{
+		{"", "foo", Info, ""},
+		{"Debug", "foo.bar", Debug, "<root>=debug"},
+		{"<root>=Error", "foo", Error, "<root>=error"},
+		{"foo.bar=Debug", "foo.bar", Debug, "foo.bar=debug"},
+		{"foo.bar=Debug", "foo.bar.baz", Debug, "foo.bar=debug"},
+		{"foo.bar=Debug", "foo.barbaz", Info, ""},
+		{"foo.bar.baz=Debug", "foo.bar", Info, ""},
+		{"foo=Trace:foo.bar=Warning", "foo.bar.baz", Warning, "foo.bar=warn"},
+		{"foo.bar=Warning:foo=Trace", "foo.bar.baz", Warning, "foo.bar=warn"},
+		{"foo.bar=Debug:foo=Trace:Info", "foo.baz", Trace, "foo=trace"},
+		{"foo.bar=Debug:foo=Trace:Info", "other", Info, "<root>=info"},
+		{"Error:foo=Debug", "foo", Debug, "foo=debug"},
+		{"*.db=Trace", "users.db", Trace, "*.db=trace"},
+		{"*.db=Trace", "db", Info, ""},
+		{"*.db=Trace", "a.b.db", Info, ""},
+		{"*.db=Trace", "users.db.pool", Trace, "*.db=trace"},
+		{"http.**=Warning", "http", Warning, "http.**=warn"},
+		{"http.**=Warning", "http.server.conn", Warning, "http.**=warn"},
+		{"**.db=Trace", "a.b.db", Trace, "**.db=trace"},
+		{"db*=Error", "dbpool", Error, "db*=error"},
+		{"http.**=Warning:http.server=Debug", "http.server.conn", Debug, "http.server=debug"},
+		{"http.*=Warning:http.**=Error", "http.server", Warning, "http.*=warn"},
+		{"foo.*.baz=Error:foo.bar=Debug", "foo.bar.baz", Error, "foo.*.baz=error"},
+		{"*.db=Trace:users.*=Error", "users.db", Error, "users.*=error"},
+		{"users.*=Error:*.db=Trace", "users.db", Trace, "*.db=trace"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec+"/"+tt.module, func(t *testing.T) {
+			spec, err := ParseSpec(tt.spec)
+			require.NoError(t, err)
+
+			rule, ok := specRule(spec, tt.module)
+			if tt.rule == "" {
+				assert.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			assert.Equal(t, tt.level, rule.Level)
+			assert.Equal(t, tt.rule, rule.String())
+		})
+	}
+}
+
+func TestSetSpec(t *testing.T) {
+	assert := assert.New(t)
+
+	setTestSpec(t, "")
+	logger := GetLogger("spec.set")
+	derived := logger.With("a", 1)
+	assert.Equal(Info, logger.LogLevel())
+
+	setTestSpec(t, "spec.set=Trace")
+	assert.Equal(Trace, logger.LogLevel(), "existing loggers should be updated")
+	assert.Equal(Trace, derived.LogLevel(), "derived loggers should be updated")
+	newLogger := GetLogger("spec.set")
+	assert.Equal(Trace, newLogger.LogLevel(), "new loggers should use the new spec")
+
+	assert.Error(SetSpec("spec.set=Loud"))
+	assert.Equal(Trace, logger.LogLevel(), "an invalid spec should not change anything")
+	assert.Equal("spec.set=trace", CurrentSpec().String())
+
+	assert.Error(ApplySpec(Spec{Rules: []Rule{{Pattern: "spec..set", Level: Debug}}}))
+	assert.NoError(ApplySpec(Spec{Rules: []Rule{{Pattern: "spec.*", Level: Debug}}}), "rules can be built without ParseSpec")
+	assert.Equal(Debug, logger.LogLevel())
+}
+
+func TestWatchSpecFile(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	setTestSpec(t, "")
+	logger := GetLogger("spec.watch")
+
+	specPath := path.Join(t.TempDir(), "logspec")
+	require.NoError(os.WriteFile(specPath, []byte("# comment\nspec.watch=Debug\n"), 0o600))
+
+	stop, err := WatchSpecFile(specPath, time.Millisecond)
+	require.NoError(err)
+	defer stop()
+	assert.Equal(Debug, logger.LogLevel())
+
+	require.NoError(os.WriteFile(specPath, []byte("spec.watch=Error\nother=Trace\n"), 0o600))
+	assert.Eventually(func() bool { return logger.LogLevel() == Error }, time.Second, time.Millisecond)
+	assert.Equal("spec.watch=error:other=trace", CurrentSpec().String())
+
+	_, err = WatchSpecFile(path.Join(t.TempDir(), "missing"), time.Millisecond)
+	assert.Error(err)
+}
+
+func TestReloadSpecOnSignal(t *testing.T) {
+	require := require.New(t)
+
+	setTestSpec(t, "")
+	logger := GetLogger("spec.signal")
+
+	specPath := path.Join(t.TempDir(), "logspec")
+	require.NoError(os.WriteFile(specPath, []byte("spec.signal=Trace"), 0o600))
+
+	stop := ReloadSpecOnSignal(specPath)
+	defer stop()
+
+	self, err := os.FindProcess(os.Getpid())
+	require.NoError(err)
+	require.NoError(self.Signal(syscall.SIGHUP))
+	assert.Eventually(t, func() bool { return logger.LogLevel() == Trace }, time.Second, time.Millisecond)
+}
+
+func TestLoggers(t *testing.T) {
+	assert := assert.New(t)
+
+	setTestSpec(t, "registry=Debug")
+	a := GetLogger("registry.a")
+	b := GetLogger("registry.a")
+	assert.Same(a.moduleState, b.moduleState, "the same name should give the same shared logger")
+
+	b.SetLogLevel(Error)
+	assert.Equal(Error, a.LogLevel())
+
+	var found []LoggerInfo
+	for _, info := range Loggers() {
+		if info.Name == "registry.a" || info.Name == "registry" {
+			found = append(found, info)
+		}
+	}
+	assert.Equal([]LoggerInfo{{Name: "registry.a", Level: Error, Rule: "registry=debug"}}, found)
+
+	infos := Loggers()
+	assert.True(slices.IsSortedFunc(infos, func(a, b LoggerInfo) int { return strings.Compare(a.Name, b.Name) }))
+}
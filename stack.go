@@ -0,0 +1,191 @@
+package logmanager
+
+import (
+	"path"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Frame is a single call in a stack trace, see Record.Frames
+type Frame struct {
+	Function string `json:"function"` // package path qualified function name, e.g. "net/http.(*Server).Serve"
+	File     string `json:"file"`     // full path of the source file
+	Line     int    `json:"line"`
+}
+
+// Path returns the file of the frame as set with SetPathStyle
+func (f Frame) Path() string {
+	switch PathStyle(atomic.LoadInt32(&pathStyle)) {
+	case PathFull:
+		return f.File
+	case PathBase:
+		return path.Base(f.File)
+	}
+
+	pkg := funcPackage(f.Function)
+	if pkg == "" {
+		return f.File
+	}
+	if pkg == "main" {
+		pkg = mainPackage()
+	}
+	return pkg + "/" + path.Base(f.File)
+}
+
+// String formats the frame as "path:line @ function"
+func (f Frame) String() string {
+	return f.Path() + ":" + strconv.Itoa(f.Line) + " @ " + f.Function
+}
+
+// PathStyle is how the files of stack frames are shown, see SetPathStyle
+type PathStyle int32
+
+// Path styles
+const (
+	// PathPackage shows the package path and the file name, e.g. "github.com/axiomhq/logmanager/record.go" or
+	// "net/http/server.go", wherever the module, GOPATH or GOROOT is on the machine that built the binary
+	PathPackage PathStyle = iota
+	// PathFull shows the full path the file was compiled from
+	PathFull
+	// PathBase shows just the file name
+	PathBase
+)
+
+var pathStyle = int32(PathPackage)
+
+// SetPathStyle sets how the files of stack frames are shown, PathPackage by default
+func SetPathStyle(style PathStyle) {
+	atomic.StoreInt32(&pathStyle, int32(style))
+}
+
+// funcPackage returns the package path of a package path qualified function name, "" if there is none
+func funcPackage(function string) string {
+	// type parameters can contain package paths of their own
+	if i := strings.IndexByte(function, '['); i != -1 {
+		function = function[:i]
+	}
+	slash := strings.LastIndexByte(function, '/')
+	dot := strings.IndexByte(function[slash+1:], '.')
+	if dot == -1 {
+		return ""
+	}
+	// dots in the last element are escaped in symbol names, e.g. "gopkg.in/yaml%2ev3.Unmarshal"
+	return strings.ReplaceAll(function[:slash+1+dot], "%2e", ".")
+}
+
+// mainPackage is the path of the main package from the build info, "main" if it's not known
+var mainPackage = sync.OnceValue(func() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Path != "" {
+		return info.Path
+	}
+	return "main"
+})
+
+var (
+	// skipPackages is replaced, never modified, so it can be used without holding the lock
+	skipPackages     = []string{"runtime", "testing", "reflect", "github.com/stretchr/testify"}
+	skipPackagesLock sync.RWMutex
+)
+
+// SetSkipPackages sets the packages whose frames are left out of stack traces because they are uninteresting,
+// a package also covers the packages below it. By default these are runtime, testing, reflect and
+// github.com/stretchr/testify
+func SetSkipPackages(packages ...string) {
+	skipPackagesLock.Lock()
+	defer skipPackagesLock.Unlock()
+	skipPackages = slices.Clone(packages)
+}
+
+// SkipPackages returns the packages left out of stack traces
+func SkipPackages() []string {
+	skipPackagesLock.RLock()
+	defer skipPackagesLock.RUnlock()
+	return slices.Clone(skipPackages)
+}
+
+// skipFrame reports whether the frame of a function is left out of stack traces, see SetSkipPackages
+func skipFrame(function string) bool {
+	skipPackagesLock.RLock()
+	packages := skipPackages
+	skipPackagesLock.RUnlock()
+
+	pkg := funcPackage(function)
+	for _, skip := range packages {
+		if pkg == skip || strings.HasPrefix(pkg, skip) && pkg[len(skip)] == '/' {
+			return true
+		}
+	}
+	return false
+}
+
+// callers returns the program counters of the current stack without the frames left out by skipFrame
+// and without the helpers at the top, skip is the number of frames to skip above the function calling callers
+func callers(skip, maxDepth int) []uintptr {
+	pcs := make([]uintptr, maxCallers)
+	pcs = pcs[:runtime.Callers(skip+2, pcs)]
+
+	stack := pcs[:0]
+	for _, pc := range pcs {
+		if len(stack) == maxDepth {
+			break
+		}
+		function := lookupCaller(pc).function
+		if len(stack) == 0 && isHelper(function) {
+			continue
+		}
+		if !skipFrame(function) {
+			stack = append(stack, pc)
+		}
+	}
+	return slices.Clip(stack)
+}
+
+// stackFrames resolves program counters as returned by runtime.Callers to at most maxDepth frames,
+// leaving out the frames of the packages set with SetSkipPackages
+func stackFrames(pcs []uintptr, maxDepth int) []Frame {
+	if len(pcs) == 0 {
+		return nil
+	}
+
+	var stack []Frame
+	frames := runtime.CallersFrames(pcs)
+	for more := true; more && len(stack) < maxDepth; {
+		var frame runtime.Frame
+		frame, more = frames.Next()
+		if !skipFrame(frame.Function) {
+			stack = append(stack, Frame{Function: frame.Function, File: frame.File, Line: frame.Line})
+		}
+	}
+	return stack
+}
+
+// StackFrames returns the current stack, most recent call first, skip is the number of frames to skip
+// like for runtime.Callers
+func StackFrames(skip, maxDepth int) []Frame {
+	pcs := make([]uintptr, maxCallers)
+	return stackFrames(pcs[:runtime.Callers(skip+1, pcs)], min(maxDepth, maxCallers))
+}
+
+// stackHeader is the line in front of every formatted stack trace
+func stackHeader(maxDepth int) string {
+	return "Trace (most recent call first, max " + strconv.Itoa(maxDepth) + " stack):\n"
+}
+
+// formatStack formats up to maxDepth+1 stack frames of pcs in columns, one line per frame
+func formatStack(pcs []uintptr, maxDepth int) string {
+	return formatFrames(stackFrames(pcs, maxDepth+1))
+}
+
+// formatFrames formats frames in columns, one line per frame
+func formatFrames(frames []Frame) string {
+	columns := columnedLines{}
+	for _, frame := range frames {
+		columns.Add("  "+frame.Path()+":"+strconv.Itoa(frame.Line), " @ "+frame.Function)
+	}
+	return columns.String()
+}
@@ -0,0 +1,89 @@
+package logmanager
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuncPackage(t *testing.T) {
+	assert := assert.New(t)
+
+	for function, pkg := range map[string]string{
+		"github.com/axiomhq/logmanager.(*Logger).Info":         "github.com/axiomhq/logmanager",
+		"github.com/axiomhq/logmanager.TestFoo.func1.2":        "github.com/axiomhq/logmanager",
+		"net/http.(*Server).Serve":                             "net/http",
+		"runtime.goexit":                                       "runtime",
+		"main.main":                                            "main",
+		"gopkg.in/yaml%2ev3.Unmarshal":                         "gopkg.in/yaml.v3",
+		"example.com/slices.Map[go.shape.int,example.com/x.T]": "example.com/slices",
+		"": "",
+	} {
+		assert.Equal(pkg, funcPackage(function), function)
+	}
+}
+
+func TestFrames(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	frames := StackFrames(1, 5)
+	require.NotEmpty(frames)
+	assert.Equal("github.com/axiomhq/logmanager.TestFrames", frames[0].Function)
+	assert.True(strings.HasSuffix(frames[0].File, "/stack_test.go"))
+	for _, frame := range frames {
+		assert.False(strings.HasPrefix(frame.Function, "testing."), "testing is skipped by default")
+	}
+
+	frame := Frame{Function: "net/http.(*Server).Serve", File: "/usr/local/go/src/net/http/server.go", Line: 3285}
+	assert.Equal("net/http/server.go:3285 @ net/http.(*Server).Serve", frame.String())
+	assert.Equal("net/http/server.go", frame.Path())
+	t.Cleanup(func() { SetPathStyle(PathPackage) })
+	SetPathStyle(PathFull)
+	assert.Equal("/usr/local/go/src/net/http/server.go", frame.Path())
+	SetPathStyle(PathBase)
+	assert.Equal("server.go", frame.Path())
+
+	orig := SkipPackages()
+	defer SetSkipPackages(orig...)
+	SetSkipPackages("github.com/axiomhq")
+	assert.NotEqual("github.com/axiomhq/logmanager.TestFrames", StackFrames(1, 5)[0].Function)
+	SetSkipPackages("github.com/axiom")
+	assert.Equal("github.com/axiomhq/logmanager.TestFrames", StackFrames(1, 5)[0].Function, "only whole path elements match")
+	SetSkipPackages()
+	assert.Equal("testing.tRunner", StackFrames(1, 5)[1].Function)
+}
+
+func TestRecordFrames(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	w := captureWriters(t)
+	var buf bytes.Buffer
+	AddWriter(NewSlogWriter(slog.NewJSONHandler(&buf, nil)))
+
+	logger := GetLogger("stack")
+	logger.IsError(errors.New("broken"))
+
+	lines := w.Lines()
+	require.Len(lines, 1)
+	r := Record{Stack: lines[0].stack}
+	frames := r.Frames()
+	require.NotEmpty(frames)
+	assert.Equal("github.com/axiomhq/logmanager.TestRecordFrames", frames[0].Function)
+	assert.Contains(r.StackTrace(), "  github.com/axiomhq/logmanager/stack_test.go:")
+
+	var logged struct {
+		Stack []Frame `json:"stack"`
+	}
+	require.NoError(json.NewDecoder(&buf).Decode(&logged))
+	assert.Equal(frames, logged.Stack)
+
+	assert.Contains(SPrintCaller(1), "  github.com/axiomhq/logmanager/stack_test.go:L")
+}
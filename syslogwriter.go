@@ -6,8 +6,9 @@ import (
 	"math/rand"
 	"net"
 	"os"
+	"path"
+	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 )
 
@@ -15,6 +16,10 @@ const rfc5424 = "2006-01-02T15:04:05.000Z07:00"
 
 var utf8bom = []byte{0xef, 0xbb, 0xbf}
 
+// syslogSDID is the SD-ID fields are sent under, 32473 is the private enterprise number
+// reserved for documentation purposes (RFC 5612)
+const syslogSDID = "fields@32473"
+
 // Most of this code imitates the golang syslog implementation
 // but as a lot of that is hard-coded to use specific (poor) formatting
 // we re-implement
@@ -60,30 +65,44 @@ func unixSyslog() (conn connwriter, err error) {
 	return nil, errors.New("unix syslog delivery error")
 }
 
+// maxSendAttempts is how often sending a message is tried, reconnecting in between, before it is dropped
+const maxSendAttempts = 3
+
 // SyslogWriter ...
 type SyslogWriter struct {
 	m sync.Mutex
 
-	isClosed  uint32
 	conn      connwriter
 	localConn bool
 	hostname  string
 	network   string
 	raddr     string
 
-	bufferedMessages chan string
+	bufferedMessages chan syslogMessage
+	done             chan struct{} // closed once sendloop has returned
 	minLevel         Level
+
+	closeLock sync.RWMutex // held for writing while bufferedMessages is closed
+	isClosed  bool
+}
+
+// syslogMessage is either a message to send or a flush request, which is acknowledged
+// by closing flushed once everything before it is sent
+type syslogMessage struct {
+	text    string
+	flushed chan struct{}
 }
 
 // NewSyslogWriter returns a writer that will send log messages to a syslog server
 // configured with the provied network, raddr strings.
 // Passing in "" as the network will default to using default unix sockets
 func NewSyslogWriter(network, raddr string) (*SyslogWriter, error) {
-	w := SyslogWriter{
+	w := &SyslogWriter{
 		network: network,
 		raddr:   raddr,
 
-		bufferedMessages: make(chan string, 1000),
+		bufferedMessages: make(chan syslogMessage, 1000),
+		done:             make(chan struct{}),
 		minLevel:         Warning,
 	}
 
@@ -92,7 +111,7 @@ func NewSyslogWriter(network, raddr string) (*SyslogWriter, error) {
 	}
 
 	go w.sendloop()
-	return &w, nil
+	return w, nil
 }
 
 func (w *SyslogWriter) connect() (err error) {
@@ -103,10 +122,9 @@ func (w *SyslogWriter) connect() (err error) {
 		w.conn = nil
 	}
 
-	if w.network == "" {
-		if w.hostname == "" {
-			w.hostname = "localhost"
-		}
+	// hostname and localConn are only set on the first connect, they are read without holding w.m
+	if w.network == "" && w.hostname == "" {
+		w.hostname = "localhost"
 		w.localConn = true
 	}
 
@@ -126,79 +144,123 @@ func (w *SyslogWriter) connect() (err error) {
 	return
 }
 
+// sendloop sends the buffered messages until the writer is closed. If sending fails it reconnects,
+// backing off for a while to stop any hammering, and drops the message after maxSendAttempts
 func (w *SyslogWriter) sendloop() {
-	// intended to be a long running goroutine
-	// uses a slice buffer and sync.Cond to syncronise instead of a channel
-	// because a channel has a limited capacity which may cause log messages
-	// to block at some point (if network goes down, there is likely to be a spike
-	// in log messages that can not be sent)
+	defer close(w.done)
 
 	var backoffCounter int32
+	for msg := range w.bufferedMessages {
+		if msg.flushed != nil {
+			close(msg.flushed)
+			continue
+		}
+
+		for attempt := 1; ; attempt++ {
+			err := w.sendMessage(msg.text)
+			if err == nil {
+				backoffCounter = 0
+				break
+			}
+			if attempt == maxSendAttempts {
+				println("Syslog-logger Warning: could not send message:", err.Error())
+				break
+			}
 
-	for {
-		ok := w.sendBufferedMessages()
-		if ok {
-			// network connection problem, backoff for a while to stop any hammering
 			if backoffCounter < 7 {
 				backoffCounter++
 			}
-
-			<-time.After((time.Millisecond * 50) * time.Duration(rand.Int31n(backoffCounter))) //nolint:gosec // This is fine here.
+			<-time.After((time.Millisecond * 50) * time.Duration(rand.Int31n(backoffCounter)+1)) //nolint:gosec // This is fine here.
+			// if reconnecting fails, the next attempt fails as well
+			_ = w.connect()
 		}
+	}
 
-		for len(w.bufferedMessages) < 1 {
-			if atomic.LoadUint32(&w.isClosed) == 1 {
-				// connection closed, we need to exit
-				return
-			}
-		}
+	w.m.Lock()
+	defer w.m.Unlock()
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
 	}
 }
 
-func (w *SyslogWriter) sendBufferedMessages() (ok bool) {
-	ok = true
+func (w *SyslogWriter) sendMessage(message string) error {
+	w.m.Lock()
+	defer w.m.Unlock()
+	if w.conn == nil {
+		return errors.New("not connected")
+	}
+	_, err := w.conn.Write([]byte(message))
+	return err
+}
 
-	for message := range w.bufferedMessages {
-		err := w.sendMessage(message)
-		if err != nil {
-			ok = false
-			break
-		}
+// Flush blocks until everything logged before it was called is sent, or dropped after failing to send
+func (w *SyslogWriter) Flush() error {
+	w.closeLock.RLock()
+	if w.isClosed {
+		w.closeLock.RUnlock()
+		return nil
 	}
-	if ok {
-		defer w.connect() //nolint:errcheck // If it fails, it fails...
+	flushed := make(chan struct{})
+	w.bufferedMessages <- syslogMessage{flushed: flushed}
+	w.closeLock.RUnlock()
+
+	<-flushed
+	return nil
+}
+
+// Close sends everything logged so far and closes the connection, anything logged afterwards is dropped
+func (w *SyslogWriter) Close() error {
+	w.closeLock.Lock()
+	if !w.isClosed {
+		w.isClosed = true
+		close(w.bufferedMessages)
 	}
-	return
+	w.closeLock.Unlock()
+
+	<-w.done
+	return nil
 }
 
-func (w *SyslogWriter) sendMessage(message string) error {
-	_, err := w.conn.Write([]byte(message))
-	return err
+// SetMinLevel sets the lowest level sent to syslog, Warning by default
+func (w *SyslogWriter) SetMinLevel(level Level) {
+	w.minLevel.Set(level)
+}
+
+// MinLevel returns the lowest level sent to syslog
+func (w *SyslogWriter) MinLevel() Level {
+	return w.minLevel.Get()
+}
+
+// Enabled ...
+func (w *SyslogWriter) Enabled(level Level, _ string) bool {
+	return level >= w.minLevel.Get()
 }
 
 // BuildTheme ...
 func (w *SyslogWriter) BuildTheme(_ /*module*/ string) ColorTheme { return ColorTheme{} }
 
 // Log ...
-func (w *SyslogWriter) Log(level Level, _ ColorTheme, module, filename string, line int, timestamp time.Time, message string) {
-	if atomic.LoadUint32(&w.isClosed) == 1 {
-		return
-	}
+func (w *SyslogWriter) Log(level Level, theme ColorTheme, module, filename string, line int, timestamp time.Time, message string) {
+	w.WriteRecord(theme, &Record{Level: level, Time: timestamp, Module: module, File: filename, Line: line, Message: message})
+}
 
+// WriteRecord ...
+func (w *SyslogWriter) WriteRecord(_ ColorTheme, r *Record) {
 	var priority int
 
 	switch {
-	case level < w.minLevel:
+	case r.Level < w.minLevel.Get():
 		return
-	case level == Debug:
+	case r.Level <= Debug:
 		priority = logDebug
-	case level == Info:
+	case r.Level == Info:
 		priority = logInfo
-	case level == Warning:
+	case r.Level == Warning:
 		priority = logWarning
-	case level == Error:
+	case r.Level == Error:
 		priority = logErr
-	case level == Critical:
+	case r.Level == Critical:
 		priority = logCrit
 	}
 
@@ -207,12 +269,59 @@ func (w *SyslogWriter) Log(level Level, _ ColorTheme, module, filename string, l
 		hostname = w.hostname
 	}
 
-	header := fmt.Sprintf("<%d>1 %s %s %s %d - -", priority, timestamp.Format(rfc5424), hostname, module, os.Getpid())
-	msg := fmt.Sprintf("%s %s%s:%d %s\n", header, utf8bom, filename, line, message)
+	header := fmt.Sprintf("<%d>1 %s %s %s %d - %s", priority, r.Time.Format(rfc5424), hostname, r.Module, os.Getpid(), structuredData(r.Fields))
+	msg := fmt.Sprintf("%s %s%s:%d %s%s\n", header, utf8bom, path.Base(r.File), r.Line, r.StackTrace(), r.Message+r.CauseTree())
+
+	w.closeLock.RLock()
+	defer w.closeLock.RUnlock()
+	if w.isClosed {
+		return
+	}
 
 	select {
-	case w.bufferedMessages <- msg:
+	case w.bufferedMessages <- syslogMessage{text: msg}:
 	default:
 		println("Syslog-logger Warning: too many messages buffered, syslog losing messages")
 	}
 }
+
+// structuredData formats fields as a RFC 5424 STRUCTURED-DATA element, or the NILVALUE if there are none
+func structuredData(fields []Field) string {
+	if len(fields) == 0 {
+		return "-"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("[" + syslogSDID)
+	for _, field := range fields {
+		sb.WriteByte(' ')
+		sb.WriteString(sdParamName(field.Key))
+		sb.WriteString(`="`)
+		sb.WriteString(sdParamValueEscaper.Replace(fieldValueString(field.Value)))
+		sb.WriteByte('"')
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
+
+// inside PARAM-VALUE the characters '"', '\' and ']' MUST be escaped
+var sdParamValueEscaper = strings.NewReplacer(`"`, `\"`, `\`, `\\`, `]`, `\]`)
+
+// sdParamName replaces everything not allowed in a PARAM-NAME and cuts it down to the maximum of 32 characters
+func sdParamName(key string) string {
+	name := []byte(key)
+	for i, c := range name {
+		if c < 33 || c > 126 || c == '=' || c == ']' || c == '"' {
+			name[i] = '_'
+		}
+	}
+
+	switch {
+	case len(name) == 0:
+		return "_"
+	case len(name) > 32:
+		return string(name[:32])
+	default:
+		return string(name)
+	}
+}